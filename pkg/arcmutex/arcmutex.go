@@ -248,3 +248,23 @@ func (am *ArcMutex[T]) IsLocked() bool {
 	}
 	return true
 }
+
+// Locker returns the mutex protecting the data, or nil if the ArcMutex[T] is
+// invalid. It exists so that other primitives, such as condvar.NewForArcMutex,
+// can wait on conditions over the protected data; locking it directly and
+// touching the data outside WithLock is not safe.
+//
+// Example:
+//
+//	queue := NewArcMutex([]int{})
+//	cv := condvar.NewForArcMutex(queue) // uses queue.Locker()
+func (am *ArcMutex[T]) Locker() sync.Locker {
+	if am == nil || am.inner == nil {
+		return nil
+	}
+	innerData := am.inner.Get()
+	if innerData == nil {
+		return nil
+	}
+	return &innerData.mu
+}
@@ -724,6 +724,40 @@ func BenchmarkArcMutexIsLocked(b *testing.B) {
 }
 
 // Example tests for documentation
+func TestArcMutexLocker(t *testing.T) {
+	am := NewArcMutex(0)
+	l := am.Locker()
+	if l == nil {
+		t.Fatal("Locker should not return nil for a valid ArcMutex")
+	}
+
+	// The returned locker is the mutex used by WithLock.
+	l.Lock()
+	if am.TryWithLock(func(_ *int) {}) {
+		t.Error("TryWithLock should fail while Locker is held")
+	}
+	l.Unlock()
+	if !am.TryWithLock(func(_ *int) {}) {
+		t.Error("TryWithLock should succeed after Locker is released")
+	}
+
+	// Clones share the same mutex.
+	clone := am.Clone()
+	if clone.Locker() != l {
+		t.Error("clones should share the same Locker")
+	}
+	clone.Drop()
+
+	am.Drop()
+	if am.Locker() != nil {
+		t.Error("Locker should return nil after Drop")
+	}
+	var nilMutex *ArcMutex[int]
+	if nilMutex.Locker() != nil {
+		t.Error("Locker should return nil for a nil ArcMutex")
+	}
+}
+
 func ExampleNewArcMutex() {
 	counter := NewArcMutex(0)
 	counter.WithLock(func(value *int) {
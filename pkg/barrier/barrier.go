@@ -1,106 +1,616 @@
 // Package barrier provides a synchronization primitive for waiting for multiple goroutines.
 // Supports atomic reference counting and state reset.
+//
+// A barrier created with NewWithAction runs an action once per generation, in
+// the goroutine that arrives last, before any participant is released; this
+// follows Java's CyclicBarrier.
+//
+// A participant that gives up, because its context is done, its timeout
+// expires or it calls Abort, breaks the current generation: every other
+// participant is released with ErrBrokenBarrier instead of waiting forever.
 package barrier
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
+// ErrActionPanicked is reported by Err when the barrier action panicked.
+var ErrActionPanicked = errors.New("barrier: action panicked")
+
+// ErrBrokenBarrier is returned by WaitContext and WaitTimeout when the current
+// generation is broken by another participant, a failing action or the last
+// Drop, and by every wait on a broken barrier until it is reset.
+var ErrBrokenBarrier = errors.New("barrier: broken")
+
 // Barrier implements a synchronization primitive for waiting for N goroutines.
+//
+// A Barrier is a handle to shared state. Like arc.Arc, every Clone returns a
+// distinct handle with its own dropped flag, and each handle must be dropped
+// exactly once. Waiting through a dropped handle fails immediately.
 type Barrier struct {
+	shared  *barrierState
+	dropped atomic.Bool
+}
+
+// barrierState is the state shared by all handles of a Barrier.
+type barrierState struct {
 	mu       sync.Mutex
-	cond     *sync.Cond
 	count    int
 	waiting  int
 	refCount atomic.Int64
-	broken   bool
-	gen      int // generation counter to distinguish cycles
+	gen      atomic.Pointer[generation] // current generation, replaced under mu
+	action   func(g *generation) error
+	collect  bool // record each participant's value, for Gather and Reduce
+	tree     bool // count arrivals on a combining tree, see tree.go
+}
+
+// Option configures a Barrier created by NewBarrier or NewWithAction, or the
+// barrier behind a Gather or Reduce.
+type Option func(*options)
+
+type options struct {
+	tree bool
+}
+
+// WithCombiningTree makes the barrier count arrivals on a combining tree of
+// atomic counters instead of under a single mutex. Arrivals are spread over
+// small groups of participants and only the goroutine that completes the
+// tree takes the barrier's lock, which relieves the contention on that lock
+// when hundreds of participants arrive at once on many cores. Each generation
+// allocates a new tree, so with few participants or few cores the default is
+// faster; compare with BenchmarkBarrierWait on the target machine.
+//
+// The API and generation semantics are unchanged, with one exception:
+// WaitResult.Index, and with it the order of the values returned by a
+// Gather, is a distinct slot in [0, n) rather than the exact arrival order.
+//
+// Example:
+//
+//	b := barrier.NewBarrier(512, barrier.WithCombiningTree())
+func WithCombiningTree() Option {
+	return func(o *options) {
+		o.tree = true
+	}
+}
+
+// generation is one cycle of the barrier. Participants wait for done to be
+// closed, which happens when the generation completes or is broken; broken and
+// cause are written before done is closed and never change afterwards.
+type generation struct {
+	id     uint64
+	done   chan struct{}
+	broken bool
+	// tripping is set while the last arrival runs the action outside s.mu;
+	// goroutines arriving meanwhile belong to the next generation.
+	tripping bool
+	cause    error          // why the generation broke, if known
+	values   []any          // values contributed in arrival order, if collected
+	result   any            // combined value, set by the action of a Reduce
+	tree     *combiningTree // arrival counters, with WithCombiningTree
+}
+
+func newGeneration(id uint64) *generation {
+	return &generation{id: id, done: make(chan struct{})}
+}
+
+// WaitResult describes how a goroutine crossed the barrier, like Rust's
+// BarrierWaitResult.
+type WaitResult struct {
+	// Index is the arrival order of the goroutine within its generation,
+	// from 0 for the first arrival to n-1 for the last.
+	Index int
+	// Generation is the number of the generation that was crossed, starting
+	// at 0 and increasing by one with every completed or reset generation.
+	Generation uint64
+
+	leader bool
+}
+
+// IsLeader returns true for exactly one goroutine per generation: the one
+// that arrived last and released the others. Use it to pick a single
+// goroutine for follow-up work after a phase.
+func (r WaitResult) IsLeader() bool {
+	return r.leader
+}
+
+// err returns the result of waiting on a finished generation.
+func (g *generation) err() error {
+	if !g.broken {
+		return nil
+	}
+	if g.cause == nil {
+		return ErrBrokenBarrier
+	}
+	return fmt.Errorf("%w: %v", ErrBrokenBarrier, g.cause)
 }
 
 // NewBarrier creates a new Barrier for n participants.
-func NewBarrier(n int) *Barrier {
+func NewBarrier(n int, opts ...Option) *Barrier {
+	return newBarrier(n, false, opts)
+}
+
+// newBarrier creates a new Barrier for n participants that records the value
+// of every participant if collect is true.
+func newBarrier(n int, collect bool, opts []Option) *Barrier {
 	if n <= 0 {
 		panic("barrier: n must be > 0")
 	}
-	b := &Barrier{count: n}
-	b.cond = sync.NewCond(&b.mu)
-	b.refCount.Store(1)
-	b.gen = 0
-	return b
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &barrierState{count: n, collect: collect, tree: o.tree}
+	s.startGenerationLocked(0)
+	s.refCount.Store(1)
+	return &Barrier{shared: s}
 }
 
-// Clone increments the reference count.
-func (b *Barrier) Clone() *Barrier {
-	b.refCount.Add(1)
+// startGenerationLocked installs a fresh generation with the given id. The
+// caller must hold s.mu, or own s exclusively.
+func (s *barrierState) startGenerationLocked(id uint64) {
+	g := newGeneration(id)
+	if s.tree {
+		g.tree = newCombiningTree(s.count)
+		if s.collect {
+			g.values = make([]any, s.count)
+		}
+	}
+	s.gen.Store(g)
+	s.waiting = 0
+}
+
+// waitingLocked returns the number of participants that have arrived in the
+// current generation. The caller must hold s.mu.
+func (s *barrierState) waitingLocked() int {
+	if s.tree {
+		g := s.gen.Load()
+		if g.broken {
+			// Like breakLocked for the mutex, nobody waits on a broken generation.
+			return 0
+		}
+		return g.tree.arrived()
+	}
+	return s.waiting
+}
+
+// NewWithAction creates a new Barrier for n participants that runs action once
+// per generation. The goroutine that completes a generation runs action before
+// any participant is released, so the action can safely merge the results of
+// the phase that just ended.
+//
+// If action returns an error or panics, the barrier is broken: every
+// participant's Wait returns false and Err reports the cause. A panic is
+// re-raised in the goroutine that ran the action after the barrier is broken.
+// Use Reset to make a broken barrier usable again.
+//
+// The action runs without the barrier's lock held, so like Java's
+// CyclicBarrier it may call Err, IsBroken, String, Abort, Reset or ForceReset.
+// A Reset from the action sets the count for the next generation. The action
+// must not wait on the barrier itself: goroutines arriving while it runs
+// belong to the next generation, which cannot start before it returns.
+//
+// Example:
+//
+//	b := barrier.NewWithAction(workers, func() error {
+//		return merge(partials)
+//	})
+func NewWithAction(n int, action func() error, opts ...Option) *Barrier {
+	b := NewBarrier(n, opts...)
+	if action != nil {
+		b.shared.action = func(*generation) error { return action() }
+	}
 	return b
 }
 
-// Drop decrements the reference count and wakes up all waiting goroutines when it reaches zero.
-func (b *Barrier) Drop() {
+// tripLocked completes generation g, which every participant has reached. It
+// runs the barrier action, if any, with s.mu released so that the action may
+// call back into the barrier, then advances to the next generation, or breaks
+// g if the action failed. It returns the result of waiting on g. The caller
+// must hold s.mu, which is held again when tripLocked returns or panics.
+func (s *barrierState) tripLocked(g *generation) error {
+	if s.action != nil {
+		g.tripping = true
+		s.mu.Unlock()
+		err, recovered := s.runAction(g)
+		s.mu.Lock()
+		g.tripping = false
+		// If g is no longer current, Reset or ForceReset already broke it.
+		if err != nil && s.gen.Load() == g {
+			s.breakLocked(err)
+		}
+		if recovered != nil {
+			panic(recovered)
+		}
+	}
+	if g.broken {
+		// Broken by the action, or by Abort, ForceReset or the last Drop
+		// while the action ran.
+		return g.err()
+	}
+	s.advanceLocked()
+	return nil
+}
+
+// runAction runs the barrier action for generation g. A panic is recovered
+// and returned, together with an error wrapping ErrActionPanicked, so that
+// the caller can break the barrier before re-raising it.
+func (s *barrierState) runAction(g *generation) (err error, recovered any) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrActionPanicked, r)
+			recovered = r
+		}
+	}()
+	return s.action(g), nil
+}
+
+// breakLocked breaks the current generation and releases every waiter. The
+// generation stays broken until Reset. The caller must hold s.mu.
+func (s *barrierState) breakLocked(cause error) {
+	g := s.gen.Load()
+	if g.broken {
+		return
+	}
+	g.broken = true
+	g.cause = cause
+	s.waiting = 0
+	close(g.done)
+}
+
+// advanceLocked completes the current generation, releasing every waiter, and
+// starts the next one. The caller must hold s.mu.
+func (s *barrierState) advanceLocked() {
+	g := s.gen.Load()
+	// Install the next generation first, so that a released goroutine that
+	// waits again finds it.
+	s.startGenerationLocked(g.id + 1)
+	close(g.done)
+}
+
+// await waits for the current generation to complete, contributing value to
+// it if the barrier collects values. A nil ctx never cancels. If ctx is done
+// first, the generation is broken and ctx.Err() is returned.
+//
+// The generation is returned so that callers can read the collected values,
+// which are final once the generation has completed.
+func (s *barrierState) await(ctx context.Context, value any) (*generation, WaitResult, error) {
+	if s.tree {
+		return s.awaitTree(ctx, value)
+	}
+	s.mu.Lock()
+	g := s.gen.Load()
+	for g.tripping {
+		// Every participant has arrived and the action is running; join the
+		// next generation once it starts. We have not arrived in g, so giving
+		// up meanwhile breaks nothing.
+		s.mu.Unlock()
+		if err := s.waitTrip(ctx, g); err != nil {
+			return nil, WaitResult{}, err
+		}
+		s.mu.Lock()
+		g = s.gen.Load()
+	}
+	if g.broken {
+		s.mu.Unlock()
+		return nil, WaitResult{}, g.err()
+	}
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			s.breakLocked(err)
+			s.mu.Unlock()
+			return nil, WaitResult{}, err
+		}
+	}
+
+	res := WaitResult{Index: s.waiting, Generation: g.id}
+	if s.collect {
+		g.values = append(g.values, value)
+	}
+	s.waiting++
+	if s.waiting == s.count {
+		// Last goroutine for this generation.
+		defer s.mu.Unlock()
+		if err := s.tripLocked(g); err != nil {
+			return nil, WaitResult{}, err
+		}
+		res.leader = true
+		return g, res, nil
+	}
+	s.mu.Unlock()
+	return s.waitDone(ctx, g, res)
+}
+
+// waitDone waits for generation g, in which the caller has arrived, to
+// complete. If ctx is done first, the generation is broken and ctx.Err() is
+// returned.
+func (s *barrierState) waitDone(ctx context.Context, g *generation, res WaitResult) (*generation, WaitResult, error) {
+	var canceled <-chan struct{}
+	if ctx != nil {
+		canceled = ctx.Done()
+	}
+	select {
+	case <-g.done:
+	case <-canceled:
+		s.mu.Lock()
+		select {
+		case <-g.done:
+			// The generation finished before we could give up.
+			s.mu.Unlock()
+		default:
+			if g.tripping {
+				// Every participant has arrived and the action is running,
+				// so it is too late to break the generation; it completes
+				// without us.
+				s.mu.Unlock()
+				return nil, WaitResult{}, ctx.Err()
+			}
+			s.breakLocked(ctx.Err())
+			s.mu.Unlock()
+			return nil, WaitResult{}, ctx.Err()
+		}
+	}
+	if err := g.err(); err != nil {
+		return nil, WaitResult{}, err
+	}
+	return g, res, nil
+}
+
+// waitTrip waits for generation g, which every participant has reached, to be
+// completed by its leader. It returns ctx.Err() if ctx is done first; a nil
+// ctx never cancels.
+func (s *barrierState) waitTrip(ctx context.Context, g *generation) error {
+	var canceled <-chan struct{}
+	if ctx != nil {
+		canceled = ctx.Done()
+	}
+	select {
+	case <-g.done:
+		return nil
+	case <-canceled:
+		return ctx.Err()
+	}
+}
+
+// handle returns the shared state if b is a live handle, or nil otherwise.
+func (b *Barrier) handle() *barrierState {
+	if b == nil || b.shared == nil || b.dropped.Load() {
+		return nil
+	}
+	return b.shared
+}
+
+// Clone creates a new handle to the barrier, incrementing the reference count.
+// It returns nil if b has been dropped.
+func (b *Barrier) Clone() *Barrier {
+	s := b.handle()
+	if s == nil {
+		return nil
+	}
 	for {
-		current := b.refCount.Load()
+		current := s.refCount.Load()
 		if current <= 0 {
-			return
+			return nil
 		}
-		if b.refCount.CompareAndSwap(current, current-1) {
-			if current-1 == 0 {
-				b.mu.Lock()
-				b.broken = true
-				b.cond.Broadcast()
-				b.mu.Unlock()
-			}
-			return
+		if s.refCount.CompareAndSwap(current, current+1) {
+			return &Barrier{shared: s}
 		}
 	}
 }
 
-// RefCount returns the current reference count.
+// Drop releases this handle and decrements the reference count. When the count
+// reaches zero, the barrier is broken and all waiting goroutines are woken up.
+// Dropping the same handle more than once has no effect.
+//
+// Returns true if this was the last reference.
+func (b *Barrier) Drop() bool {
+	if b == nil || b.shared == nil || !b.dropped.CompareAndSwap(false, true) {
+		return false
+	}
+	s := b.shared
+	if s.refCount.Add(-1) != 0 {
+		return false
+	}
+	s.mu.Lock()
+	s.breakLocked(nil)
+	s.mu.Unlock()
+	return true
+}
+
+// RefCount returns the number of live handles to the barrier.
 func (b *Barrier) RefCount() int64 {
-	return b.refCount.Load()
+	if b == nil || b.shared == nil {
+		return 0
+	}
+	return b.shared.refCount.Load()
+}
+
+// IsValid returns true if this handle has not been dropped.
+func (b *Barrier) IsValid() bool {
+	return b.handle() != nil
 }
 
 // Wait blocks the goroutine until all participants call Wait.
 // Returns true if the barrier was successfully crossed, false if the barrier was broken.
 func (b *Barrier) Wait() bool {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	if b.broken {
+	s := b.handle()
+	if s == nil {
 		return false
 	}
+	_, _, err := s.await(nil, nil)
+	return err == nil
+}
 
-	// Remember current generation.
-	myGen := b.gen
+// WaitContext blocks the goroutine until all participants have arrived or ctx
+// is done. It returns nil if the barrier was crossed.
+//
+// If ctx is done first, the current generation is broken: WaitContext returns
+// ctx.Err() and every other participant of the generation is released with an
+// error wrapping ErrBrokenBarrier. If the barrier is or becomes broken for any
+// other reason, the returned error wraps ErrBrokenBarrier, as it does when b
+// has been dropped. If ctx is done while the barrier action runs, every
+// participant has already arrived: WaitContext returns ctx.Err() without
+// breaking the generation, which completes for the others.
+//
+// Example:
+//
+//	if err := b.WaitContext(ctx); err != nil {
+//		return err // peers are released too
+//	}
+func (b *Barrier) WaitContext(ctx context.Context) error {
+	s := b.handle()
+	if s == nil {
+		return ErrBrokenBarrier
+	}
+	_, _, err := s.await(ctx, nil)
+	return err
+}
 
-	b.waiting++
-	if b.waiting == b.count {
-		// Last goroutine for this generation.
-		b.gen++            // advance generation
-		b.waiting = 0      // reset for next cycle
-		b.cond.Broadcast() // wake up all waiters
-		return true
+// WaitResult blocks like Wait and reports how the goroutine crossed the
+// barrier. Exactly one participant per generation gets a result for which
+// IsLeader returns true. If the barrier is broken, it returns an error
+// wrapping ErrBrokenBarrier instead.
+//
+// Example:
+//
+//	res, err := b.WaitResult()
+//	if err == nil && res.IsLeader() {
+//		publish(results)
+//	}
+func (b *Barrier) WaitResult() (WaitResult, error) {
+	s := b.handle()
+	if s == nil {
+		return WaitResult{}, ErrBrokenBarrier
 	}
+	_, res, err := s.await(nil, nil)
+	return res, err
+}
+
+// WaitTimeout is like WaitContext with a context that expires after d. On
+// timeout it breaks the current generation and returns
+// context.DeadlineExceeded.
+func (b *Barrier) WaitTimeout(d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return b.WaitContext(ctx)
+}
 
-	for !b.broken && myGen == b.gen {
-		b.cond.Wait()
+// Abort breaks the current generation with the given cause, releasing every
+// waiting participant with an error wrapping ErrBrokenBarrier. A worker that
+// fails and will never arrive should call Abort so its peers do not wait
+// forever. Aborting an already broken barrier has no effect.
+func (b *Barrier) Abort(cause error) {
+	s := b.handle()
+	if s == nil {
+		return
 	}
-	return !b.broken
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.breakLocked(cause)
+}
+
+// IsBroken returns true if the current generation is broken. A broken barrier
+// stays broken until Reset. It returns false if b has been dropped.
+func (b *Barrier) IsBroken() bool {
+	s := b.handle()
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.gen.Load().broken
 }
 
 // Reset resets the barrier (can only be used when no goroutines are waiting).
+// Use ForceReset to reset a barrier that may have waiters.
+// It panics if n is not positive, and has no effect if b has been dropped.
 func (b *Barrier) Reset(n int) {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	if b.waiting != 0 {
+	if n <= 0 {
+		panic("barrier: n must be > 0")
+	}
+	s := b.handle()
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.gen.Load()
+	if g.tripping {
+		// Called from the barrier action: the generation is complete, so the
+		// new count applies from the next one.
+		s.count = n
+		return
+	}
+	if s.waitingLocked() != 0 {
 		panic("barrier: cannot reset while goroutines are waiting")
 	}
-	b.count = n
-	b.broken = false
+	s.count = n
+	if s.tree && !g.broken && g.tree.size() != n {
+		// The tree is sized for the old count. Break the generation so that
+		// a goroutine racing with Reset cannot arrive in it unnoticed.
+		s.breakLocked(nil)
+	}
+	if g.broken {
+		s.startGenerationLocked(g.id + 1)
+	}
+}
+
+// ForceReset breaks the current generation, releasing every waiting
+// participant with ErrBrokenBarrier, and starts a fresh generation for n
+// participants. Unlike Reset it is safe to call while goroutines are waiting,
+// so it can resize a barrier or recover it after a failed phase. Goroutines
+// that arrive after ForceReset take part in the new generation.
+// It panics if n is not positive, and has no effect if b has been dropped.
+//
+// Example:
+//
+//	if err := b.WaitContext(ctx); err != nil {
+//		b.ForceReset(remaining)
+//	}
+func (b *Barrier) ForceReset(n int) {
+	if n <= 0 {
+		panic("barrier: n must be > 0")
+	}
+	s := b.handle()
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.breakLocked(nil)
+	s.count = n
+	s.startGenerationLocked(s.gen.Load().id + 1)
+}
+
+// Err returns the error that broke the barrier, such as the error returned by
+// a failing action, or nil if the barrier is not broken or broke because its
+// last reference was dropped. It returns nil if b has been dropped.
+func (b *Barrier) Err() error {
+	s := b.handle()
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g := s.gen.Load()
+	if !g.broken {
+		return nil
+	}
+	return g.cause
 }
 
 // String returns a string representation of the barrier.
 func (b *Barrier) String() string {
+	s := b.handle()
+	if s == nil {
+		return "Barrier{dropped}"
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	return fmt.Sprintf("Barrier{count=%d, waiting=%d, refCount=%d, broken=%v}",
-		b.count, b.waiting, b.refCount.Load(), b.broken)
+		s.count, s.waitingLocked(), s.refCount.Load(), s.gen.Load().broken)
 }
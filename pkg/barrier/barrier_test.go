@@ -1,7 +1,12 @@
 package barrier
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -208,6 +213,92 @@ func TestBarrier_Reset_WhileWaiting(t *testing.T) {
 	wg.Wait()
 }
 
+func TestBarrier_ForceReset_WhileWaiting(t *testing.T) {
+	b := NewBarrier(3)
+	defer b.Drop()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := b.WaitResult()
+			errs <- err
+		}()
+	}
+	assert.Eventually(t, func() bool {
+		return b.String() == "Barrier{count=3, waiting=2, refCount=1, broken=false}"
+	}, time.Second, time.Millisecond)
+
+	// The waiters of the interrupted generation are released as broken.
+	b.ForceReset(2)
+	assert.ErrorIs(t, <-errs, ErrBrokenBarrier)
+	assert.ErrorIs(t, <-errs, ErrBrokenBarrier)
+	assert.False(t, b.IsBroken())
+	assert.NoError(t, b.Err())
+
+	// The fresh generation uses the new count.
+	results := make(chan WaitResult, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			res, err := b.WaitResult()
+			assert.NoError(t, err)
+			results <- res
+		}()
+	}
+	first, second := <-results, <-results
+	assert.Equal(t, uint64(1), first.Generation)
+	assert.Equal(t, uint64(1), second.Generation)
+	assert.NotEqual(t, first.IsLeader(), second.IsLeader())
+
+	assert.Panics(t, func() { b.ForceReset(0) })
+}
+
+func TestBarrier_ForceReset_Broken(t *testing.T) {
+	b := NewBarrier(2)
+	defer b.Drop()
+
+	b.Abort(errors.New("phase failed"))
+	assert.True(t, b.IsBroken())
+
+	b.ForceReset(1)
+	assert.False(t, b.IsBroken())
+	assert.True(t, b.Wait())
+}
+
+func TestBarrier_ForceReset_Concurrent(t *testing.T) {
+	b := NewBarrier(4)
+	defer b.Drop()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				err := b.WaitTimeout(time.Millisecond)
+				if err != nil && !errors.Is(err, ErrBrokenBarrier) && !errors.Is(err, context.DeadlineExceeded) {
+					assert.Fail(t, "unexpected error", err)
+				}
+				if err != nil {
+					// Back off until the next reset.
+					time.Sleep(100 * time.Microsecond)
+				}
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		b.ForceReset(i%4 + 1)
+		runtime.Gosched()
+	}
+	close(stop)
+	wg.Wait()
+}
+
 func TestBarrier_String(t *testing.T) {
 	b := NewBarrier(5)
 	str := b.String()
@@ -322,3 +413,501 @@ func TestBarrier_ConcurrentReset(t *testing.T) {
 	wg.Wait()
 	// Should not panic
 }
+
+func TestBarrier_DistinctHandles(t *testing.T) {
+	b := NewBarrier(2)
+	clone := b.Clone()
+	assert.NotSame(t, b, clone)
+	assert.Equal(t, int64(2), b.RefCount())
+
+	// Each handle can take part in the barrier.
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.True(t, clone.Wait())
+	}()
+	assert.True(t, b.Wait())
+	wg.Wait()
+
+	// Dropping the same handle twice only releases one reference.
+	assert.False(t, clone.Drop())
+	assert.False(t, clone.Drop())
+	assert.Equal(t, int64(1), b.RefCount())
+	assert.False(t, clone.IsValid())
+	assert.Nil(t, clone.Clone())
+	assert.False(t, clone.Wait())
+
+	// A dropped handle no longer reaches the shared barrier.
+	b.Abort(errors.New("stop"))
+	assert.False(t, clone.IsBroken())
+	assert.NoError(t, clone.Err())
+	assert.Equal(t, "Barrier{dropped}", clone.String())
+	var nilBarrier *Barrier
+	assert.False(t, nilBarrier.IsBroken())
+	assert.NoError(t, nilBarrier.Err())
+	assert.Equal(t, "Barrier{dropped}", nilBarrier.String())
+	clone.Reset(1)
+	clone.ForceReset(1)
+	nilBarrier.Reset(1)
+	nilBarrier.ForceReset(1)
+	assert.True(t, b.IsBroken(), "resetting through a dropped handle must not reach the barrier")
+
+	assert.True(t, b.Drop())
+	assert.Equal(t, int64(0), b.RefCount())
+}
+
+func TestNewWithAction(t *testing.T) {
+	const parties = 4
+	var actions atomic.Int32
+	var phase atomic.Int32
+	b := NewWithAction(parties, func() error {
+		actions.Add(1)
+		phase.Add(1)
+		return nil
+	})
+	defer b.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < parties; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for round := int32(1); round <= 3; round++ {
+				assert.True(t, b.Wait())
+				// The action for this generation ran before anyone was released.
+				assert.GreaterOrEqual(t, phase.Load(), round)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), actions.Load(), "action must run exactly once per generation")
+	assert.NoError(t, b.Err())
+}
+
+func TestNewWithAction_Error(t *testing.T) {
+	errMerge := errors.New("merge failed")
+	b := NewWithAction(3, func() error { return errMerge })
+	defer b.Drop()
+
+	var wg sync.WaitGroup
+	results := make(chan bool, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- b.Wait()
+		}()
+	}
+	wg.Wait()
+	close(results)
+	for r := range results {
+		assert.False(t, r, "a failing action breaks the barrier for everyone")
+	}
+	assert.ErrorIs(t, b.Err(), errMerge)
+	assert.Contains(t, b.String(), "broken=true")
+
+	// Later arrivals fail fast until the barrier is reset.
+	assert.False(t, b.Wait())
+	b.Reset(1)
+	assert.NoError(t, b.Err())
+}
+
+func TestNewWithAction_Panic(t *testing.T) {
+	b := NewWithAction(3, func() error { panic("boom") })
+	defer b.Drop()
+
+	var wg sync.WaitGroup
+	var panics atomic.Int32
+	var failed atomic.Int32
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					assert.Equal(t, "boom", r)
+					panics.Add(1)
+				}
+			}()
+			if !b.Wait() {
+				failed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The panic surfaces in the goroutine that ran the action; the other
+	// participants see a broken barrier.
+	assert.Equal(t, int32(1), panics.Load())
+	assert.Equal(t, int32(2), failed.Load())
+	assert.ErrorIs(t, b.Err(), ErrActionPanicked)
+	assert.ErrorContains(t, b.Err(), "boom")
+}
+
+func TestNewWithAction_CallsBackIntoBarrier(t *testing.T) {
+	for _, mode := range []struct {
+		name string
+		opts []Option
+	}{
+		{"mutex", nil},
+		{"tree", []Option{WithCombiningTree()}},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			var b *Barrier
+			var calls atomic.Int32
+			b = NewWithAction(3, func() error {
+				if calls.Add(1) == 1 {
+					// None of these may deadlock from inside the action.
+					assert.NoError(t, b.Err())
+					assert.False(t, b.IsBroken())
+					assert.Contains(t, b.String(), "broken=false")
+					b.Reset(2)
+				}
+				return nil
+			}, mode.opts...)
+			defer b.Drop()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 3; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					assert.True(t, b.Wait())
+				}()
+			}
+			wg.Wait()
+
+			// The Reset from the action applies to the next generation.
+			done := make(chan bool)
+			go func() { done <- b.Wait() }()
+			assert.True(t, b.Wait())
+			assert.True(t, <-done)
+			assert.Equal(t, int32(2), calls.Load())
+		})
+	}
+}
+
+func TestNewWithAction_AbortFromAction(t *testing.T) {
+	cause := errors.New("merge rejected")
+	var b *Barrier
+	b = NewWithAction(2, func() error {
+		b.Abort(cause)
+		return nil
+	})
+	defer b.Drop()
+
+	done := make(chan error)
+	go func() { done <- b.WaitContext(context.Background()) }()
+	assert.ErrorIs(t, b.WaitContext(context.Background()), ErrBrokenBarrier)
+	assert.ErrorIs(t, <-done, ErrBrokenBarrier)
+	assert.Equal(t, cause, b.Err())
+}
+
+func TestNewWithAction_ForceResetFromAction(t *testing.T) {
+	var b *Barrier
+	var calls atomic.Int32
+	b = NewWithAction(2, func() error {
+		if calls.Add(1) == 1 {
+			b.ForceReset(1)
+		}
+		return nil
+	})
+	defer b.Drop()
+
+	done := make(chan bool)
+	go func() { done <- b.Wait() }()
+	assert.False(t, b.Wait())
+	assert.False(t, <-done)
+
+	// The fresh generation needs a single participant.
+	res, err := b.WaitResult()
+	assert.NoError(t, err)
+	assert.True(t, res.IsLeader())
+}
+
+func TestNewWithAction_ArrivalsDuringAction(t *testing.T) {
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var calls atomic.Int32
+	b := NewWithAction(2, func() error {
+		if calls.Add(1) == 1 {
+			close(entered)
+			<-release
+		}
+		return nil
+	})
+	defer b.Drop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() { first <- b.WaitContext(ctx) }()
+	assert.Eventually(t, func() bool { return strings.Contains(b.String(), "waiting=1") },
+		time.Second, time.Millisecond)
+	leader := make(chan WaitResult, 1)
+	go func() {
+		res, err := b.WaitResult()
+		assert.NoError(t, err)
+		leader <- res
+	}()
+	<-entered
+
+	// Giving up while the action runs is too late to break the generation,
+	// but the caller still returns without waiting for the action.
+	cancel()
+	assert.ErrorIs(t, <-first, context.Canceled)
+	// Goroutines arriving while the action runs join the next generation.
+	late := make(chan WaitResult, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			res, err := b.WaitResult()
+			assert.NoError(t, err)
+			late <- res
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, uint64(0), (<-leader).Generation)
+	assert.Equal(t, uint64(1), (<-late).Generation)
+	assert.Equal(t, uint64(1), (<-late).Generation)
+}
+
+func TestNewWithAction_WaitTimeoutDuringAction(t *testing.T) {
+	for _, mode := range []struct {
+		name string
+		opts []Option
+	}{
+		{"mutex", nil},
+		{"tree", []Option{WithCombiningTree()}},
+	} {
+		t.Run(mode.name, func(t *testing.T) {
+			entered := make(chan struct{})
+			release := make(chan struct{})
+			var calls atomic.Int32
+			b := NewWithAction(1, func() error {
+				if calls.Add(1) == 1 {
+					close(entered)
+					<-release
+				}
+				return nil
+			}, mode.opts...)
+			defer b.Drop()
+
+			leader := make(chan bool, 1)
+			go func() { leader <- b.Wait() }()
+			<-entered
+
+			// A goroutine that has not arrived yet gives up on its own
+			// timeout instead of waiting for the action.
+			start := time.Now()
+			assert.ErrorIs(t, b.WaitTimeout(20*time.Millisecond), context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			assert.False(t, b.IsBroken())
+
+			close(release)
+			assert.True(t, <-leader)
+			assert.True(t, b.Wait())
+		})
+	}
+}
+
+func TestBarrier_WaitContext(t *testing.T) {
+	b := NewBarrier(3)
+	defer b.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, b.WaitContext(context.Background()))
+		}()
+	}
+	wg.Wait()
+	assert.False(t, b.IsBroken())
+}
+
+func TestBarrier_WaitContext_CancelBreaksBarrier(t *testing.T) {
+	b := NewBarrier(4)
+	defer b.Drop()
+
+	// One peer waits without a deadline and must not hang forever.
+	peer := make(chan error, 1)
+	go func() { peer <- b.WaitContext(context.Background()) }()
+	plain := make(chan bool, 1)
+	go func() { plain <- b.Wait() }()
+	assert.Eventually(t, func() bool { return b.String() == "Barrier{count=4, waiting=2, refCount=1, broken=false}" },
+		time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	err := b.WaitContext(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrBrokenBarrier)
+
+	peerErr := <-peer
+	assert.ErrorIs(t, peerErr, ErrBrokenBarrier)
+	assert.False(t, <-plain)
+	assert.True(t, b.IsBroken())
+	assert.ErrorIs(t, b.Err(), context.Canceled)
+
+	// The barrier stays broken for later arrivals until it is reset.
+	assert.ErrorIs(t, b.WaitContext(context.Background()), ErrBrokenBarrier)
+	b.Reset(1)
+	assert.False(t, b.IsBroken())
+	assert.NoError(t, b.WaitContext(context.Background()))
+}
+
+func TestBarrier_WaitContext_AlreadyCanceled(t *testing.T) {
+	b := NewBarrier(2)
+	defer b.Drop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, b.WaitContext(ctx), context.Canceled)
+	assert.True(t, b.IsBroken())
+}
+
+func TestBarrier_WaitTimeout(t *testing.T) {
+	b := NewBarrier(2)
+	defer b.Drop()
+
+	start := time.Now()
+	assert.ErrorIs(t, b.WaitTimeout(20*time.Millisecond), context.DeadlineExceeded)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	assert.True(t, b.IsBroken())
+
+	b.Reset(2)
+	done := make(chan error, 1)
+	go func() { done <- b.WaitTimeout(time.Second) }()
+	assert.NoError(t, b.WaitTimeout(time.Second))
+	assert.NoError(t, <-done)
+}
+
+func TestBarrier_Abort(t *testing.T) {
+	b := NewBarrier(3)
+	defer b.Drop()
+
+	errWorker := errors.New("worker failed")
+	results := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { results <- b.WaitContext(context.Background()) }()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	b.Abort(errWorker)
+	for i := 0; i < 2; i++ {
+		err := <-results
+		assert.ErrorIs(t, err, ErrBrokenBarrier)
+		assert.ErrorContains(t, err, "worker failed")
+	}
+	assert.ErrorIs(t, b.Err(), errWorker)
+
+	// A second Abort keeps the original cause.
+	b.Abort(errors.New("other"))
+	assert.ErrorIs(t, b.Err(), errWorker)
+}
+
+func TestBarrier_WaitContext_Dropped(t *testing.T) {
+	b := NewBarrier(2)
+	clone := b.Clone()
+	clone.Drop()
+	assert.ErrorIs(t, clone.WaitContext(context.Background()), ErrBrokenBarrier)
+
+	done := make(chan error, 1)
+	go func() { done <- b.WaitContext(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+	b.Drop()
+	assert.ErrorIs(t, <-done, ErrBrokenBarrier)
+}
+
+func TestBarrier_WaitContext_RaceWithCompletion(t *testing.T) {
+	// Cancel exactly as the generation completes: every participant must get
+	// a consistent result, either all crossing or the generation broken.
+	for i := 0; i < 100; i++ {
+		b := NewBarrier(2)
+		ctx, cancel := context.WithCancel(context.Background())
+		res := make(chan error, 1)
+		go func() { res <- b.WaitContext(ctx) }()
+		go cancel()
+		other := b.WaitContext(context.Background())
+		mine := <-res
+		if other == nil {
+			assert.NoError(t, mine)
+		} else {
+			assert.ErrorIs(t, other, ErrBrokenBarrier)
+			assert.ErrorIs(t, mine, context.Canceled)
+		}
+		b.Drop()
+	}
+}
+
+func TestBarrier_WaitResult(t *testing.T) {
+	const parties = 5
+	b := NewBarrier(parties)
+	defer b.Drop()
+
+	for gen := uint64(0); gen < 3; gen++ {
+		results := make(chan WaitResult, parties)
+		var wg sync.WaitGroup
+		for i := 0; i < parties; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := b.WaitResult()
+				assert.NoError(t, err)
+				results <- res
+			}()
+		}
+		wg.Wait()
+		close(results)
+
+		leaders := 0
+		seen := make(map[int]bool)
+		for res := range results {
+			assert.Equal(t, gen, res.Generation)
+			seen[res.Index] = true
+			if res.IsLeader() {
+				leaders++
+				assert.Equal(t, parties-1, res.Index, "the last arrival leads")
+			}
+		}
+		assert.Equal(t, 1, leaders)
+		assert.Len(t, seen, parties, "arrival indexes must be distinct")
+	}
+}
+
+func TestBarrier_WaitResult_MixedWithWait(t *testing.T) {
+	b := NewBarrier(2)
+	defer b.Drop()
+
+	done := make(chan bool)
+	go func() { done <- b.Wait() }()
+	res, err := b.WaitResult()
+	assert.NoError(t, err)
+	assert.True(t, <-done, "Wait keeps returning a bool")
+	assert.Equal(t, uint64(0), res.Generation)
+}
+
+func TestBarrier_WaitResult_Broken(t *testing.T) {
+	b := NewBarrier(2)
+	b.Abort(errors.New("stop"))
+	res, err := b.WaitResult()
+	assert.ErrorIs(t, err, ErrBrokenBarrier)
+	assert.False(t, res.IsLeader())
+
+	// Reset starts a new generation number.
+	b.Reset(1)
+	res, err = b.WaitResult()
+	assert.NoError(t, err)
+	assert.True(t, res.IsLeader())
+	assert.Equal(t, uint64(1), res.Generation)
+
+	b.Drop()
+	_, err = b.WaitResult()
+	assert.ErrorIs(t, err, ErrBrokenBarrier)
+}
@@ -0,0 +1,243 @@
+package barrier
+
+import (
+	"context"
+	"strings"
+)
+
+// Gather is an all-gather barrier: every participant contributes a value and,
+// once all have arrived, each receives the values of the whole generation.
+// Like Barrier it is cyclic, and the same rules for breaking apply: a
+// participant that gives up breaks the generation for everyone.
+//
+// A Gather is a handle to shared state; each handle must be dropped exactly
+// once.
+type Gather[T any] struct {
+	b *Barrier
+}
+
+// NewGather creates a new Gather for n participants.
+//
+// Example:
+//
+//	g := barrier.NewGather[int](workers)
+//	for i := 0; i < workers; i++ {
+//		go func() {
+//			partials := g.Wait(compute(i))
+//			use(partials)
+//		}()
+//	}
+func NewGather[T any](n int, opts ...Option) *Gather[T] {
+	b := newBarrier(n, true, opts)
+	return &Gather[T]{b: b}
+}
+
+// Wait contributes value and blocks until every participant has arrived. It
+// returns the values of the generation in arrival order, or in slot order
+// with WithCombiningTree; the slice is the caller's own. It returns nil if
+// the barrier was broken.
+func (g *Gather[T]) Wait(value T) []T {
+	values, err := g.wait(nil, value)
+	if err != nil {
+		return nil
+	}
+	return values
+}
+
+// WaitContext is like Wait but gives up when ctx is done, breaking the
+// generation like Barrier.WaitContext. It returns the values of the
+// generation in arrival order, or an error if the barrier was broken.
+func (g *Gather[T]) WaitContext(ctx context.Context, value T) ([]T, error) {
+	return g.wait(ctx, value)
+}
+
+// wait implements Wait and WaitContext; a nil ctx never cancels.
+func (g *Gather[T]) wait(ctx context.Context, value T) ([]T, error) {
+	s := g.b.handle()
+	if s == nil {
+		return nil, ErrBrokenBarrier
+	}
+	gen, _, err := s.await(ctx, value)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]T, len(gen.values))
+	for i, v := range gen.values {
+		values[i] = valueAs[T](v)
+	}
+	return values, nil
+}
+
+// Clone creates a new handle to the barrier, incrementing the reference count.
+// It returns nil if g has been dropped.
+func (g *Gather[T]) Clone() *Gather[T] {
+	b := g.b.Clone()
+	if b == nil {
+		return nil
+	}
+	return &Gather[T]{b: b}
+}
+
+// Drop releases this handle. Dropping the last handle breaks the barrier and
+// releases every waiter. Returns true if this was the last reference.
+func (g *Gather[T]) Drop() bool {
+	return g.b.Drop()
+}
+
+// Abort breaks the current generation with the given cause, like
+// Barrier.Abort.
+func (g *Gather[T]) Abort(cause error) {
+	g.b.Abort(cause)
+}
+
+// IsBroken returns true if the current generation is broken. A broken Gather
+// stays broken until Reset or ForceReset.
+func (g *Gather[T]) IsBroken() bool {
+	return g.b.IsBroken()
+}
+
+// Err returns the error that broke the barrier, such as the cause passed to
+// Abort, or nil if the barrier is not broken.
+func (g *Gather[T]) Err() error {
+	return g.b.Err()
+}
+
+// Reset makes the barrier usable again for n participants, like
+// Barrier.Reset. It panics if goroutines are waiting.
+func (g *Gather[T]) Reset(n int) {
+	g.b.Reset(n)
+}
+
+// ForceReset breaks the current generation and starts a fresh one for n
+// participants, like Barrier.ForceReset.
+func (g *Gather[T]) ForceReset(n int) {
+	g.b.ForceReset(n)
+}
+
+// String returns a string representation of the barrier.
+func (g *Gather[T]) String() string {
+	return "Gather" + strings.TrimPrefix(g.b.String(), "Barrier")
+}
+
+// Reduce is a barrier that combines the values of every participant. Once
+// all have arrived, the value of the generation is folded with the combine
+// function in arrival order (slot order with WithCombiningTree), and every
+// participant receives the result.
+// combine runs once per generation, in the goroutine that arrives last,
+// before anyone is released. If combine panics, the barrier is broken as if
+// a barrier action had panicked.
+//
+// A Reduce is a handle to shared state; each handle must be dropped exactly
+// once.
+type Reduce[T any] struct {
+	b *Barrier
+}
+
+// NewReduce creates a new Reduce for n participants.
+//
+// Example:
+//
+//	sum := barrier.NewReduce(workers, func(a, b int) int { return a + b })
+//	total := sum.Wait(partial)
+func NewReduce[T any](n int, combine func(a, b T) T, opts ...Option) *Reduce[T] {
+	b := newBarrier(n, true, opts)
+	s := b.shared
+	s.action = func(g *generation) error {
+		// Runs before the generation advances, once every value is in.
+		acc := valueAs[T](g.values[0])
+		for _, v := range g.values[1:] {
+			acc = combine(acc, valueAs[T](v))
+		}
+		g.result = acc
+		return nil
+	}
+	return &Reduce[T]{b: b}
+}
+
+// Wait contributes value and blocks until every participant has arrived. It
+// returns the combined value of the generation and true, or the zero value
+// and false if the barrier was broken.
+func (r *Reduce[T]) Wait(value T) (T, bool) {
+	result, err := r.wait(nil, value)
+	return result, err == nil
+}
+
+// WaitContext is like Wait but gives up when ctx is done, breaking the
+// generation like Barrier.WaitContext. It returns the combined value of the
+// generation, or an error if the barrier was broken.
+func (r *Reduce[T]) WaitContext(ctx context.Context, value T) (T, error) {
+	return r.wait(ctx, value)
+}
+
+// wait implements Wait and WaitContext; a nil ctx never cancels.
+func (r *Reduce[T]) wait(ctx context.Context, value T) (T, error) {
+	var zero T
+	s := r.b.handle()
+	if s == nil {
+		return zero, ErrBrokenBarrier
+	}
+	gen, _, err := s.await(ctx, value)
+	if err != nil {
+		return zero, err
+	}
+	return valueAs[T](gen.result), nil
+}
+
+// Clone creates a new handle to the barrier, incrementing the reference count.
+// It returns nil if r has been dropped.
+func (r *Reduce[T]) Clone() *Reduce[T] {
+	b := r.b.Clone()
+	if b == nil {
+		return nil
+	}
+	return &Reduce[T]{b: b}
+}
+
+// Drop releases this handle. Dropping the last handle breaks the barrier and
+// releases every waiter. Returns true if this was the last reference.
+func (r *Reduce[T]) Drop() bool {
+	return r.b.Drop()
+}
+
+// Abort breaks the current generation with the given cause, like
+// Barrier.Abort.
+func (r *Reduce[T]) Abort(cause error) {
+	r.b.Abort(cause)
+}
+
+// IsBroken returns true if the current generation is broken. A broken Reduce
+// stays broken until Reset or ForceReset.
+func (r *Reduce[T]) IsBroken() bool {
+	return r.b.IsBroken()
+}
+
+// Reset makes the barrier usable again for n participants, like
+// Barrier.Reset. It panics if goroutines are waiting.
+func (r *Reduce[T]) Reset(n int) {
+	r.b.Reset(n)
+}
+
+// ForceReset breaks the current generation and starts a fresh one for n
+// participants, like Barrier.ForceReset.
+func (r *Reduce[T]) ForceReset(n int) {
+	r.b.ForceReset(n)
+}
+
+// Err returns the error that broke the barrier, such as a panic in combine,
+// or nil if the barrier is not broken.
+func (r *Reduce[T]) Err() error {
+	return r.b.Err()
+}
+
+// String returns a string representation of the barrier.
+func (r *Reduce[T]) String() string {
+	return "Reduce" + strings.TrimPrefix(r.b.String(), "Barrier")
+}
+
+// valueAs converts a value recorded by the barrier back to T. A nil interface
+// value, such as a nil error contributed by a participant, becomes the zero
+// value of T instead of panicking.
+func valueAs[T any](v any) T {
+	t, _ := v.(T)
+	return t
+}
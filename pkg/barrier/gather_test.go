@@ -0,0 +1,243 @@
+package barrier
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGather_Wait(t *testing.T) {
+	const n = 5
+	g := NewGather[int](n)
+	defer g.Drop()
+
+	for round := 0; round < 3; round++ {
+		results := make([][]int, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = g.Wait(round*10 + i)
+			}(i)
+		}
+		wg.Wait()
+
+		want := []int{round * 10, round*10 + 1, round*10 + 2, round*10 + 3, round*10 + 4}
+		for i, got := range results {
+			assert.Len(t, got, n)
+			// Every participant sees the same values in the same order.
+			assert.Equal(t, results[0], got)
+			sorted := append([]int(nil), got...)
+			sort.Ints(sorted)
+			assert.Equal(t, want, sorted, "participant %d", i)
+		}
+		// Each participant owns its slice.
+		results[0][0] = -1
+		assert.NotEqual(t, -1, results[1][0])
+	}
+}
+
+func TestGather_ArrivalOrder(t *testing.T) {
+	g := NewGather[string](3)
+	defer g.Drop()
+
+	results := make(chan []string, 3)
+	arrive := func(v string) {
+		go func() { results <- g.Wait(v) }()
+	}
+	arrive("first")
+	assert.Eventually(t, func() bool { return g.b.String() == "Barrier{count=3, waiting=1, refCount=1, broken=false}" }, time.Second, time.Millisecond)
+	arrive("second")
+	assert.Eventually(t, func() bool { return g.b.String() == "Barrier{count=3, waiting=2, refCount=1, broken=false}" }, time.Second, time.Millisecond)
+	arrive("third")
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, []string{"first", "second", "third"}, <-results)
+	}
+}
+
+func TestGather_Broken(t *testing.T) {
+	g := NewGather[int](2)
+	clone := g.Clone()
+	assert.Equal(t, "Gather{count=2, waiting=0, refCount=2, broken=false}", g.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	values, err := clone.WaitContext(ctx, 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, values)
+	assert.Nil(t, g.Wait(2))
+
+	assert.False(t, clone.Drop())
+	assert.Nil(t, clone.Clone())
+	_, err = clone.WaitContext(context.Background(), 1)
+	assert.ErrorIs(t, err, ErrBrokenBarrier)
+	assert.True(t, g.Drop())
+}
+
+func TestReduce_Wait(t *testing.T) {
+	const n = 8
+	sum := NewReduce(n, func(a, b int) int { return a + b })
+	defer sum.Drop()
+
+	for round := 1; round <= 3; round++ {
+		var wg sync.WaitGroup
+		for i := 1; i <= n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				total, ok := sum.Wait(i * round)
+				assert.True(t, ok)
+				assert.Equal(t, 36*round, total)
+			}(i)
+		}
+		wg.Wait()
+	}
+}
+
+func TestReduce_CombineOncePerGeneration(t *testing.T) {
+	const n = 4
+	var mu sync.Mutex
+	calls := 0
+	r := NewReduce(n, func(a, b []int) []int {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return append(append([]int(nil), a...), b...)
+	})
+	defer r.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			all, ok := r.Wait([]int{i})
+			assert.True(t, ok)
+			assert.Len(t, all, n)
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, n-1, calls)
+}
+
+func TestReduce_CombinePanics(t *testing.T) {
+	r := NewReduce(2, func(a, b int) int { panic("boom") })
+	defer r.Drop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := r.WaitContext(context.Background(), 1)
+		errCh <- err
+	}()
+	assert.Eventually(t, func() bool { return r.String() == "Reduce{count=2, waiting=1, refCount=1, broken=false}" }, time.Second, time.Millisecond)
+
+	assert.PanicsWithValue(t, "boom", func() { r.Wait(2) })
+	assert.ErrorIs(t, <-errCh, ErrBrokenBarrier)
+	assert.True(t, errors.Is(r.Err(), ErrActionPanicked))
+}
+
+func TestReduce_Abort(t *testing.T) {
+	r := NewReduce(3, func(a, b int) int { return a + b })
+	defer r.Drop()
+
+	cause := errors.New("worker failed")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		r.Abort(cause)
+	}()
+	total, ok := r.Wait(1)
+	assert.False(t, ok)
+	assert.Zero(t, total)
+	assert.Equal(t, cause, r.Err())
+}
+
+func TestGather_RecoverAfterAbort(t *testing.T) {
+	g := NewGather[int](2)
+	defer g.Drop()
+
+	cause := errors.New("worker failed")
+	g.Abort(cause)
+	assert.True(t, g.IsBroken())
+	assert.Equal(t, cause, g.Err())
+	assert.Nil(t, g.Wait(1))
+
+	g.Reset(2)
+	assert.False(t, g.IsBroken())
+	assert.NoError(t, g.Err())
+	results := make(chan []int)
+	go func() { results <- g.Wait(1) }()
+	values := g.Wait(2)
+	sort.Ints(values)
+	assert.Equal(t, []int{1, 2}, values)
+	assert.Len(t, <-results, 2)
+
+	// ForceReset recovers a broken Gather even with a new party count.
+	g.Abort(cause)
+	g.ForceReset(1)
+	assert.Equal(t, []int{3}, g.Wait(3))
+}
+
+func TestReduce_RecoverAfterAbort(t *testing.T) {
+	r := NewReduce(2, func(a, b int) int { return a + b })
+	defer r.Drop()
+
+	r.Abort(errors.New("worker failed"))
+	assert.True(t, r.IsBroken())
+	_, ok := r.Wait(1)
+	assert.False(t, ok)
+
+	r.Reset(2)
+	assert.False(t, r.IsBroken())
+	totals := make(chan int)
+	go func() {
+		total, _ := r.Wait(1)
+		totals <- total
+	}()
+	total, ok := r.Wait(2)
+	assert.True(t, ok)
+	assert.Equal(t, 3, total)
+	assert.Equal(t, 3, <-totals)
+
+	// The combine function survives a ForceReset.
+	r.Abort(errors.New("worker failed"))
+	r.ForceReset(1)
+	total, ok = r.Wait(5)
+	assert.True(t, ok)
+	assert.Equal(t, 5, total)
+}
+
+func TestGather_NilInterfaceValues(t *testing.T) {
+	g := NewGather[error](2)
+	defer g.Drop()
+
+	failed := errors.New("worker failed")
+	results := make(chan []error, 1)
+	go func() { results <- g.Wait(nil) }()
+	errs := g.Wait(failed)
+
+	assert.ElementsMatch(t, []error{nil, failed}, errs)
+	assert.ElementsMatch(t, []error{nil, failed}, <-results)
+}
+
+func TestReduce_NilInterfaceValues(t *testing.T) {
+	r := NewReduce(2, func(a, b error) error { return errors.Join(a, b) })
+	defer r.Drop()
+
+	results := make(chan error, 1)
+	go func() {
+		err, ok := r.Wait(nil)
+		assert.True(t, ok)
+		results <- err
+	}()
+	err, ok := r.Wait(nil)
+	assert.True(t, ok)
+	assert.NoError(t, err)
+	assert.NoError(t, <-results)
+}
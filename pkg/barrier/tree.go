@@ -0,0 +1,178 @@
+package barrier
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// treeFanIn is the number of children of each node in a combining tree.
+const treeFanIn = 4
+
+// combiningTree counts the arrivals of one generation. Participants claim a
+// slot in a leaf, and the last arrival at each node moves up to its parent,
+// so every counter is shared by at most treeFanIn goroutines. The goroutine
+// that completes the root completes the generation.
+//
+// A fresh tree is built for every generation, so counters never need to be
+// reset and a goroutine holding a stale generation cannot disturb the next
+// one.
+type combiningTree struct {
+	nodes  []treeNode // leaves first, root last
+	leaves int
+}
+
+// treeNode is a counter of the combining tree, padded to its own cache line.
+type treeNode struct {
+	claimed atomic.Int32 // slots handed out; leaves only
+	arrived atomic.Int32
+	size    int32 // arrivals that complete the node
+	parent  int32 // index of the parent node, or -1 for the root
+	base    int32 // first slot of a leaf
+	_       [44]byte
+}
+
+// newCombiningTree builds a tree for n participants.
+func newCombiningTree(n int) *combiningTree {
+	leaves := (n + treeFanIn - 1) / treeFanIn
+	total := leaves
+	for width := leaves; width > 1; {
+		width = (width + treeFanIn - 1) / treeFanIn
+		total += width
+	}
+
+	t := &combiningTree{nodes: make([]treeNode, total), leaves: leaves}
+	for i := 0; i < leaves; i++ {
+		t.nodes[i].size = int32(min(treeFanIn, n-i*treeFanIn))
+		t.nodes[i].base = int32(i * treeFanIn)
+	}
+	// Group each level's nodes under the next level, up to the root.
+	start, width := 0, leaves
+	for width > 1 {
+		next := start + width
+		for i := 0; i < width; i++ {
+			parent := next + i/treeFanIn
+			t.nodes[start+i].parent = int32(parent)
+			t.nodes[parent].size++
+		}
+		start, width = next, (width+treeFanIn-1)/treeFanIn
+	}
+	t.nodes[total-1].parent = -1
+	return t
+}
+
+// claim reserves a slot in a leaf that is not yet full, starting from a
+// random leaf to spread concurrent arrivals. It returns false if every slot
+// of the generation has been taken.
+func (t *combiningTree) claim() (leaf, slot int, ok bool) {
+	start := rand.IntN(t.leaves)
+	for i := 0; i < t.leaves; i++ {
+		leaf = (start + i) % t.leaves
+		n := &t.nodes[leaf]
+		for {
+			c := n.claimed.Load()
+			if c >= n.size {
+				break
+			}
+			if n.claimed.CompareAndSwap(c, c+1) {
+				return leaf, int(n.base + c), true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// arrive records an arrival at leaf. It returns true if the arrival completed
+// the root, and with it the generation.
+func (t *combiningTree) arrive(leaf int) bool {
+	for i := int32(leaf); i >= 0; i = t.nodes[i].parent {
+		n := &t.nodes[i]
+		if n.arrived.Add(1) != n.size {
+			return false
+		}
+	}
+	return true
+}
+
+// arrived returns the number of participants that have arrived.
+func (t *combiningTree) arrived() int {
+	total := 0
+	for i := 0; i < t.leaves; i++ {
+		total += int(t.nodes[i].arrived.Load())
+	}
+	return total
+}
+
+// size returns the number of participants the tree was built for.
+func (t *combiningTree) size() int {
+	total := 0
+	for i := 0; i < t.leaves; i++ {
+		total += int(t.nodes[i].size)
+	}
+	return total
+}
+
+// awaitTree is await for a barrier created with WithCombiningTree. Arrivals
+// only touch the counters of the current generation's tree; the lock is taken
+// by the goroutine that completes the generation and by those that give up.
+func (s *barrierState) awaitTree(ctx context.Context, value any) (*generation, WaitResult, error) {
+	for {
+		g := s.gen.Load()
+		select {
+		case <-g.done:
+			if g.broken {
+				return nil, WaitResult{}, g.err()
+			}
+			// The generation has just completed; join the next one.
+			continue
+		default:
+		}
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				s.mu.Lock()
+				if s.gen.Load().tripping {
+					// The action is running and we have not arrived, so
+					// there is nothing to break, as without a tree.
+					s.mu.Unlock()
+					return nil, WaitResult{}, err
+				}
+				s.breakLocked(err)
+				s.mu.Unlock()
+				return nil, WaitResult{}, err
+			}
+		}
+
+		leaf, slot, ok := g.tree.claim()
+		if !ok {
+			// Every participant of this generation has arrived and the leader
+			// is completing it; join the next one, as a goroutine blocked on
+			// the lock would without a tree.
+			if err := s.waitTrip(ctx, g); err != nil {
+				return nil, WaitResult{}, err
+			}
+			continue
+		}
+		if s.collect {
+			// Written before arriving, so the leader and, through done,
+			// every participant see it.
+			g.values[slot] = value
+		}
+		res := WaitResult{Index: slot, Generation: g.id}
+		if !g.tree.arrive(leaf) {
+			return s.waitDone(ctx, g, res)
+		}
+
+		// Last goroutine for this generation.
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if g.broken {
+			// Broken while we were arriving, possibly by Reset or ForceReset.
+			return nil, WaitResult{}, g.err()
+		}
+		if err := s.tripLocked(g); err != nil {
+			return nil, WaitResult{}, err
+		}
+		res.leader = true
+		return g, res, nil
+	}
+}
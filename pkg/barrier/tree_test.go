@@ -0,0 +1,275 @@
+package barrier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombiningTree_Shape(t *testing.T) {
+	for _, n := range []int{1, 3, 4, 5, 16, 17, 64, 513} {
+		tree := newCombiningTree(n)
+		assert.Equal(t, n, tree.size(), "n=%d", n)
+
+		root := &tree.nodes[len(tree.nodes)-1]
+		assert.Equal(t, int32(-1), root.parent, "n=%d", n)
+		// Every node but the root has a parent, and each parent is
+		// completed by exactly its children.
+		children := make([]int32, len(tree.nodes))
+		for i := range tree.nodes[:len(tree.nodes)-1] {
+			parent := tree.nodes[i].parent
+			assert.Greater(t, parent, int32(i), "n=%d", n)
+			children[parent]++
+		}
+		for i := tree.leaves; i < len(tree.nodes); i++ {
+			assert.Equal(t, tree.nodes[i].size, children[i], "n=%d node=%d", n, i)
+			assert.LessOrEqual(t, tree.nodes[i].size, int32(treeFanIn))
+		}
+	}
+}
+
+func TestCombiningTree_Generations(t *testing.T) {
+	const (
+		n      = 37
+		rounds = 20
+	)
+	b := NewBarrier(n, WithCombiningTree())
+	defer b.Drop()
+
+	var mu sync.Mutex
+	indexes := make(map[uint64][]int)
+	leaders := make(map[uint64]int)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				res, err := b.WaitResult()
+				if !assert.NoError(t, err) {
+					return
+				}
+				mu.Lock()
+				indexes[res.Generation] = append(indexes[res.Generation], res.Index)
+				if res.IsLeader() {
+					leaders[res.Generation]++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, indexes, rounds)
+	for gen, idx := range indexes {
+		sort.Ints(idx)
+		for i, v := range idx {
+			assert.Equal(t, i, v, "generation %d has a duplicate or missing slot", gen)
+		}
+		assert.Equal(t, 1, leaders[gen], "generation %d", gen)
+	}
+	assert.Equal(t, "Barrier{count=37, waiting=0, refCount=1, broken=false}", b.String())
+}
+
+func TestCombiningTree_Action(t *testing.T) {
+	const n = 9
+	var phase atomic.Int32
+	b := NewWithAction(n, func() error {
+		phase.Add(1)
+		return nil
+	}, WithCombiningTree())
+	defer b.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := int32(1); r <= 5; r++ {
+				assert.True(t, b.Wait())
+				// The action for this generation ran before anyone was released.
+				assert.GreaterOrEqual(t, phase.Load(), r)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(5), phase.Load())
+}
+
+func TestCombiningTree_Cancel(t *testing.T) {
+	b := NewBarrier(6, WithCombiningTree())
+	defer b.Drop()
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() { errs <- b.WaitContext(context.Background()) }()
+	}
+	assert.Eventually(t, func() bool {
+		return b.String() == "Barrier{count=6, waiting=4, refCount=1, broken=false}"
+	}, time.Second, time.Millisecond)
+
+	assert.ErrorIs(t, b.WaitTimeout(10*time.Millisecond), context.DeadlineExceeded)
+	for i := 0; i < 4; i++ {
+		assert.ErrorIs(t, <-errs, ErrBrokenBarrier)
+	}
+	assert.True(t, b.IsBroken())
+	assert.ErrorIs(t, b.WaitContext(context.Background()), ErrBrokenBarrier)
+
+	// Reset makes the barrier usable again, with a freshly sized tree.
+	b.Reset(2)
+	assert.False(t, b.IsBroken())
+	done := make(chan bool)
+	go func() { done <- b.Wait() }()
+	assert.True(t, b.Wait())
+	assert.True(t, <-done)
+}
+
+func TestCombiningTree_ResetResizes(t *testing.T) {
+	b := NewBarrier(3, WithCombiningTree())
+	defer b.Drop()
+
+	b.Reset(1)
+	res, err := b.WaitResult()
+	assert.NoError(t, err)
+	assert.True(t, res.IsLeader())
+	assert.Equal(t, 0, res.Index)
+}
+
+func TestCombiningTree_ResetNonPositive(t *testing.T) {
+	b := NewBarrier(2, WithCombiningTree())
+	defer b.Drop()
+
+	assert.PanicsWithValue(t, "barrier: n must be > 0", func() { b.Reset(0) })
+	done := make(chan bool)
+	go func() { done <- b.Wait() }()
+	assert.True(t, b.Wait())
+	assert.True(t, <-done)
+
+	// From the action, the panic breaks the barrier instead of the next
+	// generation's tree.
+	var a *Barrier
+	a = NewWithAction(1, func() error {
+		a.Reset(0)
+		return nil
+	}, WithCombiningTree())
+	defer a.Drop()
+	assert.PanicsWithValue(t, "barrier: n must be > 0", func() { a.Wait() })
+	assert.ErrorIs(t, a.Err(), ErrActionPanicked)
+}
+
+func TestCombiningTree_ForceReset(t *testing.T) {
+	b := NewBarrier(4, WithCombiningTree())
+	defer b.Drop()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { errs <- b.WaitContext(context.Background()) }()
+	}
+	assert.Eventually(t, func() bool {
+		return b.String() == "Barrier{count=4, waiting=2, refCount=1, broken=false}"
+	}, time.Second, time.Millisecond)
+
+	b.ForceReset(2)
+	assert.ErrorIs(t, <-errs, ErrBrokenBarrier)
+	assert.ErrorIs(t, <-errs, ErrBrokenBarrier)
+
+	go func() { errs <- b.WaitContext(context.Background()) }()
+	assert.NoError(t, b.WaitContext(context.Background()))
+	assert.NoError(t, <-errs)
+}
+
+func TestCombiningTree_Drop(t *testing.T) {
+	b := NewBarrier(3, WithCombiningTree())
+	clone := b.Clone()
+
+	done := make(chan bool)
+	go func() { done <- clone.Wait() }()
+	assert.Eventually(t, func() bool {
+		return b.String() == "Barrier{count=3, waiting=1, refCount=2, broken=false}"
+	}, time.Second, time.Millisecond)
+
+	assert.False(t, b.Drop())
+	assert.True(t, clone.Drop())
+	assert.False(t, <-done)
+}
+
+func TestCombiningTree_GatherAndReduce(t *testing.T) {
+	const n = 23
+	g := NewGather[int](n, WithCombiningTree())
+	defer g.Drop()
+	sum := NewReduce(n, func(a, b int) int { return a + b }, WithCombiningTree())
+	defer sum.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for r := 0; r < 3; r++ {
+				values := g.Wait(i)
+				sorted := append([]int(nil), values...)
+				sort.Ints(sorted)
+				for j, v := range sorted {
+					assert.Equal(t, j, v)
+				}
+				total, ok := sum.Wait(i)
+				assert.True(t, ok)
+				assert.Equal(t, n*(n-1)/2, total)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCombiningTree_AbortCause(t *testing.T) {
+	b := NewBarrier(2, WithCombiningTree())
+	defer b.Drop()
+
+	cause := errors.New("worker failed")
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Abort(cause)
+	}()
+	err := b.WaitContext(context.Background())
+	assert.ErrorIs(t, err, ErrBrokenBarrier)
+	assert.Equal(t, cause, b.Err())
+}
+
+// BenchmarkBarrierWait measures one generation crossed by every participant,
+// with and without the combining tree.
+func BenchmarkBarrierWait(b *testing.B) {
+	for _, n := range []int{8, 64, 512} {
+		for _, mode := range []struct {
+			name string
+			opts []Option
+		}{
+			{"mutex", nil},
+			{"tree", []Option{WithCombiningTree()}},
+		} {
+			b.Run(fmt.Sprintf("%s/%d", mode.name, n), func(b *testing.B) {
+				bar := NewBarrier(n, mode.opts...)
+				defer bar.Drop()
+
+				var wg sync.WaitGroup
+				b.ResetTimer()
+				for i := 0; i < n; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for j := 0; j < b.N; j++ {
+							bar.Wait()
+						}
+					}()
+				}
+				wg.Wait()
+			})
+		}
+	}
+}
@@ -1,142 +1,483 @@
 // Package condvar provides conditional variables for goroutine coordination
 // with atomic reference counting, similar to sync.Cond but with Arc semantics.
+//
+// Waiting goroutines are kept on an explicit wait list and parked on a channel,
+// so context-aware waits need no helper goroutine: a canceled waiter simply
+// removes itself from the list and never consumes a wakeup meant for another.
 package condvar
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/Gosayram/gokoncurent/pkg/arcmutex"
 )
 
+// ErrClosed is returned when waiting on or signaling through a dropped CondVar
+// handle, or a CondVar whose last reference has been dropped.
+var ErrClosed = errors.New("condvar: condition variable has been dropped")
+
 // CondVar represents a conditional variable with atomic reference counting.
 // It provides a way for goroutines to wait for a condition to become true
 // while maintaining thread-safe reference counting.
+//
+// A CondVar is a handle to shared state. Like arc.Arc, every Clone returns a
+// distinct handle with its own dropped flag, and each handle must be dropped
+// exactly once. Dropping the last handle closes the CondVar: goroutines
+// still waiting are woken with ErrClosed (or false), and waiting on or
+// signaling through a dropped handle fails the same way instead of blocking.
 type CondVar struct {
-	mu       sync.Mutex
-	cond     *sync.Cond
+	shared  *condState
+	dropped atomic.Bool
+}
+
+// condState is the state shared by all handles of a CondVar.
+type condState struct {
+	lock sync.Locker
+	// external is set when lock was supplied by the caller. Wait and
+	// WaitWithContext then expect it to be held, like sync.Cond.
+	external bool
+	waiters  waitList
 	refCount atomic.Int64
+	closed   atomic.Bool // set when the last handle is dropped
+}
+
+// Option configures a CondVar created by NewCondVar, NewWithLocker or
+// NewForArcMutex.
+type Option func(*options)
+
+type options struct {
+	lifo bool
+}
+
+// WithLIFO makes Signal and SignalN wake the most recently arrived waiter
+// first. That goroutine is the likeliest to still be warm in cache and is
+// cheaper to resume, but early waiters can starve under heavy contention.
+// By default waiters are woken in arrival order, like sync.Cond.
+//
+// Example:
+//
+//	cv := NewCondVar(WithLIFO())
+func WithLIFO() Option {
+	return func(o *options) {
+		o.lifo = true
+	}
 }
 
 // NewCondVar creates a new conditional variable with initial reference count of 1.
-func NewCondVar() *CondVar {
-	cv := &CondVar{}
-	cv.cond = sync.NewCond(&cv.mu)
-	cv.refCount.Store(1)
-	return cv
+func NewCondVar(opts ...Option) *CondVar {
+	return newCondVar(&sync.Mutex{}, false, opts)
 }
 
-// Clone creates a new reference to the conditional variable, incrementing the reference count.
-func (cv *CondVar) Clone() *CondVar {
-	cv.refCount.Add(1)
-	return cv
+// NewWithLocker creates a new conditional variable associated with l, with
+// initial reference count of 1. This lets goroutines wait on a condition
+// protected by a lock they already use, such as a sync.Mutex or the mutex of
+// an ArcMutex (see NewForArcMutex).
+//
+// With an external lock, Wait, WaitWithContext and WaitWithTimeout must be
+// called with l held: they release l while waiting and reacquire it before
+// returning. Lock and Unlock lock and unlock l. It panics if l is nil.
+//
+// Example:
+//
+//	var mu sync.Mutex
+//	cv := NewWithLocker(&mu)
+//	mu.Lock()
+//	for !ready {
+//		cv.Wait()
+//	}
+//	mu.Unlock()
+func NewWithLocker(l sync.Locker, opts ...Option) *CondVar {
+	if l == nil {
+		panic("condvar: nil locker")
+	}
+	return newCondVar(l, true, opts)
+}
+
+// NewForArcMutex creates a new conditional variable that uses the mutex of am,
+// so conditions on the value protected by am can be waited for from inside
+// am.WithLock. It panics if am has been dropped.
+//
+// Example:
+//
+//	queue := arcmutex.NewArcMutex([]int{})
+//	cv := NewForArcMutex(queue)
+//	queue.WithLock(func(q *[]int) {
+//		cv.WaitForLocked(func() bool { return len(*q) > 0 })
+//		*q = (*q)[1:]
+//	})
+func NewForArcMutex[T any](am *arcmutex.ArcMutex[T], opts ...Option) *CondVar {
+	l := am.Locker()
+	if l == nil {
+		panic("condvar: ArcMutex has been dropped")
+	}
+	return newCondVar(l, true, opts)
+}
+
+// newCondVar creates the shared state and the first handle of a CondVar.
+func newCondVar(l sync.Locker, external bool, opts []Option) *CondVar {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &condState{lock: l, external: external}
+	s.waiters.lifo = o.lifo
+	s.refCount.Store(1)
+	return &CondVar{shared: s}
+}
+
+// enqueue registers the calling goroutine as a waiter. It returns nil if the
+// CondVar is closed; checking after registering guarantees that a concurrent
+// final Drop either is observed here or wakes the new waiter.
+func (s *condState) enqueue() *waiter {
+	w := s.waiters.enqueue()
+	if s.closed.Load() {
+		s.waiters.cancel(w)
+		return nil
+	}
+	return w
+}
+
+// handle returns the shared state if cv is a live handle, or nil otherwise.
+func (cv *CondVar) handle() *condState {
+	if cv == nil || cv.shared == nil || cv.dropped.Load() {
+		return nil
+	}
+	return cv.shared
 }
 
-// Drop decrements the reference count. When the count reaches zero,
-// the conditional variable is considered "dropped" and should not be used further.
-func (cv *CondVar) Drop() {
+// Clone creates a new handle to the conditional variable, incrementing the reference count.
+// It returns nil if cv has been dropped.
+func (cv *CondVar) Clone() *CondVar {
+	s := cv.handle()
+	if s == nil {
+		return nil
+	}
 	for {
-		current := cv.refCount.Load()
+		current := s.refCount.Load()
 		if current <= 0 {
-			return // Already dropped or invalid
+			return nil
 		}
-		if cv.refCount.CompareAndSwap(current, current-1) {
-			if current-1 == 0 {
-				// Wake up all waiting goroutines when dropping the last reference
-				cv.mu.Lock()
-				cv.cond.Broadcast()
-				cv.mu.Unlock()
-			}
-			return
+		if s.refCount.CompareAndSwap(current, current+1) {
+			return &CondVar{shared: s}
 		}
 	}
 }
 
-// RefCount returns the current reference count.
+// Drop releases this handle and decrements the reference count. When the count reaches zero,
+// the conditional variable is closed and every waiting goroutine is woken with ErrClosed.
+// Dropping the same handle more than once has no effect.
+//
+// Returns true if this was the last reference.
+func (cv *CondVar) Drop() bool {
+	if cv == nil || cv.shared == nil || !cv.dropped.CompareAndSwap(false, true) {
+		return false
+	}
+	s := cv.shared
+	if s.refCount.Add(-1) != 0 {
+		return false
+	}
+	// Close before waking, so that woken waiters observe the closed state
+	// and new waiters never park.
+	s.closed.Store(true)
+	s.waiters.signal(-1)
+	return true
+}
+
+// RefCount returns the number of live handles to the conditional variable.
+// It is zero once the CondVar is closed.
 func (cv *CondVar) RefCount() int64 {
-	return cv.refCount.Load()
+	if cv == nil || cv.shared == nil {
+		return 0
+	}
+	return cv.shared.refCount.Load()
+}
+
+// IsValid returns true if this handle has not been dropped.
+func (cv *CondVar) IsValid() bool {
+	return cv.handle() != nil
+}
+
+// IsClosed returns true once the last handle to the CondVar has been dropped.
+func (cv *CondVar) IsClosed() bool {
+	return cv == nil || cv.shared == nil || cv.shared.closed.Load()
 }
 
 // Wait waits for the condition to be signaled. It atomically unlocks the mutex
 // and suspends execution of the calling goroutine until the condition is signaled.
-func (cv *CondVar) Wait() {
-	cv.mu.Lock()
-	defer cv.mu.Unlock()
-	cv.cond.Wait()
+//
+// For a CondVar created with NewWithLocker or NewForArcMutex the caller must
+// hold the lock, which is released while waiting and reacquired on return.
+//
+// It returns ErrClosed if cv has been dropped or the CondVar is closed while
+// waiting.
+func (cv *CondVar) Wait() error {
+	s := cv.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	w := s.enqueue()
+	if w == nil {
+		return ErrClosed
+	}
+	if s.external {
+		s.lock.Unlock()
+		defer s.lock.Lock()
+	}
+	<-w.ready
+	if s.closed.Load() {
+		return ErrClosed
+	}
+	return nil
 }
 
-// WaitWithContext waits for the condition to be signaled or context cancellation.
-// Returns true if the condition was signaled, false if context was canceled.
-func (cv *CondVar) WaitWithContext(ctx context.Context) bool {
-	// Use a buffered channel to avoid goroutine leak
-	done := make(chan bool, 1)
+// WaitLocked waits for the condition to be signaled. The caller must hold the
+// lock acquired with Lock; WaitLocked atomically releases it while waiting and
+// reacquires it before returning, like sync.Cond.Wait.
+//
+// Because the goroutine may wake up without the condition being true, callers
+// should re-check their condition in a loop, or use WaitForLocked.
+// It returns ErrClosed under the same conditions as Wait.
+func (cv *CondVar) WaitLocked() error {
+	s := cv.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	w := s.enqueue()
+	if w == nil {
+		return ErrClosed
+	}
+	s.lock.Unlock()
+	<-w.ready
+	s.lock.Lock()
+	if s.closed.Load() {
+		return ErrClosed
+	}
+	return nil
+}
+
+// WaitFor blocks until pred returns true. pred is always called with the
+// CondVar's lock held, and the lock is held continuously between a false
+// result of pred and the start of the wait, so a Signal or Broadcast issued
+// after changing the state under Lock can never be lost. WaitFor acquires and
+// releases the lock itself, including a lock supplied to NewWithLocker.
+//
+// Returns true once pred is satisfied, or false if the CondVar is dropped
+// before that happens.
+//
+// Example:
+//
+//	cv.Lock()
+//	ready = true
+//	cv.Unlock()
+//	cv.Broadcast()
+//
+//	// elsewhere
+//	cv.WaitFor(func() bool { return ready })
+func (cv *CondVar) WaitFor(pred func() bool) bool {
+	s := cv.handle()
+	if s == nil {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return cv.WaitForLocked(pred)
+}
+
+// WaitForLocked is like WaitFor, but the caller must already hold the lock
+// acquired with Lock. The lock is still held when WaitForLocked returns.
+func (cv *CondVar) WaitForLocked(pred func() bool) bool {
+	s := cv.handle()
+	if s == nil {
+		return false
+	}
+	for !pred() {
+		w := s.enqueue()
+		if w == nil {
+			return false
+		}
+		s.lock.Unlock()
+		<-w.ready
+		s.lock.Lock()
+	}
+	return true
+}
+
+// WaitForContext blocks until pred returns true or ctx is done. pred is called
+// with the CondVar's lock held, as in WaitFor.
+//
+// Returns nil once pred is satisfied, ctx.Err() if the context is done first,
+// or ErrClosed if the CondVar is dropped.
+func (cv *CondVar) WaitForContext(ctx context.Context, pred func() bool) error {
+	s := cv.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return cv.WaitForContextLocked(ctx, pred)
+}
 
-	go func() {
-		cv.mu.Lock()
-		defer cv.mu.Unlock()
-		cv.cond.Wait()
+// WaitForContextLocked is like WaitForContext, but the caller must already
+// hold the lock acquired with Lock. The lock is still held when
+// WaitForContextLocked returns.
+func (cv *CondVar) WaitForContextLocked(ctx context.Context, pred func() bool) error {
+	s := cv.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	woken := false // the last wait ended with a signal
+	for !pred() {
+		if err := ctx.Err(); err != nil {
+			if woken {
+				// The signal we consumed found the predicate false and we
+				// are giving up; hand it to another waiter.
+				s.waiters.signal(1)
+			}
+			return err
+		}
+		w := s.enqueue()
+		if w == nil {
+			return ErrClosed
+		}
+		s.lock.Unlock()
 		select {
-		case done <- true:
-		default:
+		case <-w.ready:
+			s.lock.Lock()
+			woken = true
+		case <-ctx.Done():
+			signaled := !s.waiters.cancel(w)
+			s.lock.Lock()
+			if pred() {
+				return nil
+			}
+			if signaled {
+				// We are giving up, so hand the wakeup we consumed to
+				// another waiter instead of losing it.
+				s.waiters.signal(1)
+			}
+			return ctx.Err()
 		}
-	}()
+	}
+	return nil
+}
 
+// WaitWithContext waits for the condition to be signaled or context cancellation.
+// Returns true if the condition was signaled, false if context was canceled
+// or the CondVar is closed.
+//
+// A canceled waiter leaves the wait list without consuming a Signal meant for
+// another goroutine. If the signal and the cancellation race, the signal wins
+// and WaitWithContext returns true.
+//
+// For a CondVar created with NewWithLocker or NewForArcMutex the caller must
+// hold the lock, which is released while waiting and reacquired on return.
+func (cv *CondVar) WaitWithContext(ctx context.Context) bool {
+	s := cv.handle()
+	if s == nil {
+		return false
+	}
+	w := s.enqueue()
+	if w == nil {
+		return false
+	}
+	if s.external {
+		s.lock.Unlock()
+		defer s.lock.Lock()
+	}
 	select {
-	case result := <-done:
-		return result
+	case <-w.ready:
 	case <-ctx.Done():
-		// Wake up the waiting goroutine
-		cv.mu.Lock()
-		cv.cond.Signal()
-		cv.mu.Unlock()
-		return false
+		if s.waiters.cancel(w) {
+			return false
+		}
 	}
+	return !s.closed.Load()
 }
 
 // WaitWithTimeout waits for the condition to be signaled with a timeout.
-// Returns true if the condition was signaled, false if timeout occurred.
+// Returns true if the condition was signaled, false if timeout occurred or the
+// CondVar is closed. Locking requirements are the same as for WaitWithContext.
 func (cv *CondVar) WaitWithTimeout(timeout time.Duration) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 	return cv.WaitWithContext(ctx)
 }
 
-// Signal wakes up one goroutine waiting on the condition.
-func (cv *CondVar) Signal() {
-	cv.mu.Lock()
-	defer cv.mu.Unlock()
-	cv.cond.Signal()
+// Signal wakes up one goroutine waiting on the condition: the one that has
+// waited longest, or the most recent one with WithLIFO.
+// It returns ErrClosed, without waking anyone, if cv has been dropped.
+func (cv *CondVar) Signal() error {
+	s := cv.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	s.waiters.signal(1)
+	return nil
+}
+
+// SignalN wakes up to n goroutines waiting on the condition and returns how
+// many were woken. It returns 0 and ErrClosed if cv has been dropped.
+func (cv *CondVar) SignalN(n int) (int, error) {
+	s := cv.handle()
+	if s == nil {
+		return 0, ErrClosed
+	}
+	if n <= 0 {
+		return 0, nil
+	}
+	return s.waiters.signal(n), nil
+}
+
+// Waiters returns the number of goroutines currently waiting on the condition.
+// The result is a snapshot: waiters may arrive or leave right after it is taken.
+func (cv *CondVar) Waiters() int {
+	if cv == nil || cv.shared == nil {
+		return 0
+	}
+	return cv.shared.waiters.len()
 }
 
 // Broadcast wakes up all goroutines waiting on the condition.
-func (cv *CondVar) Broadcast() {
-	cv.mu.Lock()
-	defer cv.mu.Unlock()
-	cv.cond.Broadcast()
+// It returns ErrClosed, without waking anyone, if cv has been dropped.
+func (cv *CondVar) Broadcast() error {
+	s := cv.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	s.waiters.signal(-1)
+	return nil
 }
 
-// Lock locks the underlying mutex.
+// Lock locks the underlying mutex, or the lock supplied to NewWithLocker.
+// Hold it while changing the state that waiters check in their predicate,
+// and while calling the *Locked wait methods.
+// Signal and Broadcast may be called with or without the mutex held.
 func (cv *CondVar) Lock() {
-	cv.mu.Lock()
+	cv.shared.lock.Lock()
 }
 
 // Unlock unlocks the underlying mutex.
 func (cv *CondVar) Unlock() {
-	cv.mu.Unlock()
+	cv.shared.lock.Unlock()
 }
 
 // String returns a string representation of the conditional variable.
 func (cv *CondVar) String() string {
-	return fmt.Sprintf("CondVar{refCount: %d}", cv.refCount.Load())
+	return fmt.Sprintf("CondVar{refCount: %d}", cv.RefCount())
 }
 
 // Notify is a convenience function that creates a new CondVar and returns it
 // along with a function to signal it. This is useful for simple notification patterns.
+// A signal sent while nobody waits is lost; notify.Notify stores it instead.
 func Notify() (cv *CondVar, signal func()) {
 	cv = NewCondVar()
 	signal = func() {
-		cv.Signal()
+		_ = cv.Signal()
 	}
 	return cv, signal
 }
@@ -146,7 +487,7 @@ func Notify() (cv *CondVar, signal func()) {
 func NotifyBroadcast() (cv *CondVar, broadcast func()) {
 	cv = NewCondVar()
 	broadcast = func() {
-		cv.Broadcast()
+		_ = cv.Broadcast()
 	}
 	return cv, broadcast
 }
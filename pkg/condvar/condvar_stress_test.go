@@ -37,20 +37,26 @@ func TestCondVarConcurrentSignalBroadcast(t *testing.T) {
 		time.Sleep(500 * time.Microsecond)
 	}
 
-	// Ensure any remaining waiters are released
-	cv.Broadcast()
-
 	done := make(chan struct{})
 	go func() {
 		wg.Wait()
 		close(done)
 	}()
 
-	select {
-	case <-done:
-		// success
-	case <-time.After(2 * time.Second):
-		t.Fatal("timeout waiting for goroutines to finish; possible deadlock")
+	// A waiter that was between two Wait calls misses a broadcast, so keep
+	// broadcasting until every waiter has finished its iterations.
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(2 * time.Second)
+	for released := false; !released; {
+		cv.Broadcast()
+		select {
+		case <-done:
+			released = true
+		case <-ticker.C:
+		case <-timeout:
+			t.Fatal("timeout waiting for goroutines to finish; possible deadlock")
+		}
 	}
 
 	if cv.RefCount() != 1 {
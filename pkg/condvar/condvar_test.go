@@ -3,11 +3,14 @@ package condvar
 import (
 	"context"
 	"fmt"
+	"runtime"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/Gosayram/gokoncurent/pkg/arcmutex"
 )
 
 func TestNewCondVar(t *testing.T) {
@@ -381,3 +384,607 @@ func TestCondVar_StressTest(t *testing.T) {
 	wg.Wait()
 	assert.Equal(t, int64(50), counter)
 }
+
+func TestCondVar_DistinctHandles(t *testing.T) {
+	cv := NewCondVar()
+	clone := cv.Clone()
+	assert.NotSame(t, cv, clone)
+	assert.Equal(t, int64(2), cv.RefCount())
+
+	// Dropping the same handle twice only releases one reference.
+	assert.False(t, clone.Drop())
+	assert.False(t, clone.Drop())
+	assert.Equal(t, int64(1), cv.RefCount())
+	assert.False(t, clone.IsValid())
+	assert.Nil(t, clone.Clone())
+
+	// The remaining handle still works.
+	assert.True(t, cv.IsValid())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		cv.Wait()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cv.Signal()
+	wg.Wait()
+
+	assert.True(t, cv.Drop())
+	assert.Equal(t, int64(0), cv.RefCount())
+}
+
+func TestCondVar_WaitFor(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	ready := false
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.True(t, cv.WaitFor(func() bool { return ready }))
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cv.Lock()
+	ready = true
+	cv.Unlock()
+	cv.Broadcast()
+	wg.Wait()
+
+	// The predicate already holds, so WaitFor must not block even though
+	// nobody signals again.
+	assert.True(t, cv.WaitFor(func() bool { return ready }))
+}
+
+func TestCondVar_WaitFor_NoLostWakeup(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	// A producer that signals between the consumer's check and its wait must
+	// not be missed: every item must be consumed.
+	const items = 1000
+	queue := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for consumed := 0; consumed < items; consumed++ {
+			cv.Lock()
+			cv.WaitForLocked(func() bool { return queue > 0 })
+			queue--
+			cv.Unlock()
+		}
+	}()
+
+	for i := 0; i < items; i++ {
+		cv.Lock()
+		queue++
+		cv.Unlock()
+		cv.Signal()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer missed a wakeup")
+	}
+}
+
+func TestCondVar_WaitForContext(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := cv.WaitForContext(ctx, func() bool { return false })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	ready := false
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cv.Lock()
+		ready = true
+		cv.Unlock()
+		cv.Signal()
+	}()
+	assert.NoError(t, cv.WaitForContext(context.Background(), func() bool { return ready }))
+}
+
+func TestCondVar_WaitForContext_CancelDoesNotWakeOthers(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	ready := false
+	otherDone := make(chan error, 1)
+	go func() {
+		otherDone <- cv.WaitForContext(context.Background(), func() bool { return ready })
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		cancelled <- cv.WaitForContext(ctx, func() bool { return ready })
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-cancelled, context.Canceled)
+
+	// The other waiter's predicate is still false, so it keeps waiting.
+	select {
+	case err := <-otherDone:
+		t.Fatalf("waiter returned early: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cv.Lock()
+	ready = true
+	cv.Unlock()
+	cv.Broadcast()
+	assert.NoError(t, <-otherDone)
+}
+
+func TestCondVar_WaitLocked(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	ready := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cv.Lock()
+		for !ready {
+			cv.WaitLocked()
+		}
+		cv.Unlock()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cv.Lock()
+	ready = true
+	cv.Signal() // allowed with or without the lock held
+	cv.Unlock()
+	<-done
+}
+
+func TestCondVar_WaitFor_Dropped(t *testing.T) {
+	cv := NewCondVar()
+	clone := cv.Clone()
+	clone.Drop()
+	assert.False(t, clone.WaitFor(func() bool { return true }))
+	assert.ErrorIs(t, clone.WaitForContext(context.Background(), func() bool { return true }), ErrClosed)
+
+	// Dropping the last reference releases predicate waiters.
+	waitFor := make(chan bool, 1)
+	waitForCtx := make(chan error, 1)
+	go func() { waitFor <- cv.WaitFor(func() bool { return false }) }()
+	go func() { waitForCtx <- cv.WaitForContext(context.Background(), func() bool { return false }) }()
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, cv.Drop())
+	assert.False(t, <-waitFor)
+	assert.ErrorIs(t, <-waitForCtx, ErrClosed)
+}
+
+func TestCondVar_WaitWithContext_NoGoroutineLeak(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		assert.False(t, cv.WaitWithContext(ctx))
+		cancel()
+		assert.False(t, cv.WaitWithTimeout(time.Millisecond))
+		assert.ErrorIs(t, cv.WaitForContext(ctx, func() bool { return false }), context.DeadlineExceeded)
+	}
+
+	// No helper goroutines are spawned, so none can be left parked.
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestCondVar_WaitWithContext_NoGoroutinesWhileWaiting(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	const waiters = 10
+	ctx, cancel := context.WithCancel(context.Background())
+	before := runtime.NumGoroutine()
+
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.False(t, cv.WaitWithContext(ctx))
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	// Only the waiters themselves exist; no per-wait helpers.
+	assert.Equal(t, before+waiters, runtime.NumGoroutine())
+
+	cancel()
+	wg.Wait()
+	// Poll by hand: assert.Eventually runs its condition on a goroutine.
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > before && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestCondVar_WaitWithContext_CancelDoesNotStealSignal(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	other := make(chan bool, 1)
+	go func() { other <- cv.WaitWithContext(context.Background()) }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan bool, 1)
+	go func() { canceled <- cv.WaitWithContext(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+
+	cancel()
+	assert.False(t, <-canceled)
+
+	// The canceled waiter has left the wait list, so the single Signal must
+	// reach the remaining waiter.
+	cv.Signal()
+	select {
+	case ok := <-other:
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("signal was lost to a canceled waiter")
+	}
+}
+
+func TestCondVar_WaitForContext_ForwardsConsumedSignal(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ready := false
+	started := make(chan struct{})
+	res := make(chan error, 1)
+	go func() {
+		cv.Lock()
+		close(started)
+		res <- cv.WaitForContextLocked(ctx, func() bool { return ready })
+		cv.Unlock()
+	}()
+	<-started
+
+	next := make(chan bool, 1)
+	go func() { next <- cv.WaitWithContext(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// Signal and cancel together: whichever waiter consumed the signal, the
+	// one still waiting must end up woken.
+	cv.Signal()
+	cancel()
+	err := <-res
+	assert.ErrorIs(t, err, context.Canceled)
+	select {
+	case ok := <-next:
+		assert.True(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("wakeup consumed by a canceled predicate waiter was lost")
+	}
+}
+
+func TestNewWithLocker(t *testing.T) {
+	assert.Panics(t, func() { NewWithLocker(nil) })
+
+	var mu sync.Mutex
+	cv := NewWithLocker(&mu)
+	defer cv.Drop()
+
+	ready := false
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mu.Lock()
+		for !ready {
+			cv.Wait()
+		}
+		// Wait reacquired the external lock before returning.
+		assert.False(t, mu.TryLock())
+		mu.Unlock()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	// The waiter released mu while waiting, so it can be taken here.
+	mu.Lock()
+	ready = true
+	cv.Signal()
+	mu.Unlock()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("waiter on external lock was not woken")
+	}
+}
+
+func TestNewWithLocker_WaitWithContextAndTimeout(t *testing.T) {
+	var mu sync.Mutex
+	cv := NewWithLocker(&mu)
+	defer cv.Drop()
+
+	mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.False(t, cv.WaitWithContext(ctx))
+	assert.False(t, mu.TryLock(), "lock must be held again after WaitWithContext")
+	assert.False(t, cv.WaitWithTimeout(10*time.Millisecond))
+	assert.False(t, mu.TryLock(), "lock must be held again after WaitWithTimeout")
+
+	go func() {
+		mu.Lock()
+		cv.Signal()
+		mu.Unlock()
+	}()
+	assert.True(t, cv.WaitWithTimeout(time.Second))
+	mu.Unlock()
+
+	// Lock and Unlock operate on the external lock.
+	cv.Lock()
+	assert.False(t, mu.TryLock())
+	cv.Unlock()
+	assert.True(t, mu.TryLock())
+	mu.Unlock()
+}
+
+func TestNewWithLocker_RWMutexReadLocker(t *testing.T) {
+	var rw sync.RWMutex
+	cv := NewWithLocker(rw.RLocker())
+	defer cv.Drop()
+
+	version := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.True(t, cv.WaitFor(func() bool { return version > 0 }))
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	// The waiter does not hold the read lock while parked, so a writer can
+	// get in.
+	rw.Lock()
+	version++
+	rw.Unlock()
+	cv.Broadcast()
+	<-done
+}
+
+func TestNewForArcMutex(t *testing.T) {
+	queue := arcmutex.NewArcMutex([]int{})
+	defer queue.Drop()
+	cv := NewForArcMutex(queue)
+	defer cv.Drop()
+
+	const items = 100
+	var got []int
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for len(got) < items {
+			queue.WithLock(func(q *[]int) {
+				cv.WaitForLocked(func() bool { return len(*q) > 0 })
+				got = append(got, (*q)[0])
+				*q = (*q)[1:]
+			})
+		}
+	}()
+
+	for i := 0; i < items; i++ {
+		queue.WithLock(func(q *[]int) { *q = append(*q, i) })
+		cv.Signal()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("consumer did not receive all items")
+	}
+	for i, v := range got {
+		assert.Equal(t, i, v)
+	}
+
+	dropped := arcmutex.NewArcMutex(0)
+	dropped.Drop()
+	assert.Panics(t, func() { NewForArcMutex(dropped) })
+}
+
+func TestCondVar_Closed(t *testing.T) {
+	cv := NewCondVar()
+	clone := cv.Clone()
+
+	// Waiters of every kind are parked when the last handle goes away.
+	waitErr := make(chan error, 1)
+	waitCtx := make(chan bool, 1)
+	waitFor := make(chan bool, 1)
+	go func() { waitErr <- clone.Wait() }()
+	go func() { waitCtx <- clone.WaitWithContext(context.Background()) }()
+	go func() { waitFor <- clone.WaitFor(func() bool { return false }) }()
+	time.Sleep(10 * time.Millisecond)
+
+	assert.False(t, cv.Drop())
+	assert.False(t, cv.IsClosed())
+	assert.ErrorIs(t, cv.Signal(), ErrClosed, "dropped handle cannot signal")
+
+	// The waiters above use clone, which is still live; dropping it closes
+	// the CondVar from under them.
+	assert.True(t, clone.Drop())
+	assert.True(t, clone.IsClosed())
+	assert.ErrorIs(t, <-waitErr, ErrClosed)
+	assert.False(t, <-waitCtx)
+	assert.False(t, <-waitFor)
+
+	// Future calls fail immediately instead of blocking.
+	assert.ErrorIs(t, clone.Wait(), ErrClosed)
+	assert.False(t, clone.WaitWithTimeout(time.Second))
+	assert.ErrorIs(t, clone.WaitForContext(context.Background(), func() bool { return false }), ErrClosed)
+	assert.ErrorIs(t, clone.Signal(), ErrClosed)
+	assert.ErrorIs(t, clone.Broadcast(), ErrClosed)
+	assert.Equal(t, int64(0), cv.RefCount())
+	assert.Equal(t, int64(0), clone.RefCount())
+	assert.Nil(t, clone.Clone())
+}
+
+func TestCondVar_ClosedExternalLock(t *testing.T) {
+	var mu sync.Mutex
+	cv := NewWithLocker(&mu)
+	clone := cv.Clone()
+
+	done := make(chan error, 1)
+	go func() {
+		mu.Lock()
+		err := clone.Wait()
+		// The lock is reacquired even when the wait ends because of Drop.
+		assert.False(t, mu.TryLock())
+		mu.Unlock()
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	cv.Drop()
+	clone.Drop()
+	assert.ErrorIs(t, <-done, ErrClosed)
+}
+
+func TestCondVar_SignalReturnsNil(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+	assert.NoError(t, cv.Signal())
+	assert.NoError(t, cv.Broadcast())
+	assert.False(t, cv.IsClosed())
+
+	var nilCV *CondVar
+	assert.ErrorIs(t, nilCV.Signal(), ErrClosed)
+	assert.ErrorIs(t, nilCV.Wait(), ErrClosed)
+	assert.True(t, nilCV.IsClosed())
+}
+
+// parkWaiters starts n goroutines that wait on cv one after another, so their
+// arrival order is known, and returns the channel they report their id on.
+func parkWaiters(t *testing.T, cv *CondVar, n int) <-chan int {
+	t.Helper()
+	woken := make(chan int, n)
+	for i := 0; i < n; i++ {
+		go func(id int) {
+			if cv.Wait() == nil {
+				woken <- id
+			}
+		}(i)
+		assert.Eventually(t, func() bool { return cv.Waiters() == i+1 }, time.Second, time.Millisecond)
+	}
+	return woken
+}
+
+func TestCondVar_Waiters(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+	assert.Equal(t, 0, cv.Waiters())
+
+	woken := parkWaiters(t, cv, 3)
+	assert.Equal(t, 3, cv.Waiters())
+
+	assert.NoError(t, cv.Signal())
+	<-woken
+	assert.Equal(t, 2, cv.Waiters())
+
+	// A canceled waiter leaves the wait list.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool)
+	go func() { done <- cv.WaitWithContext(ctx) }()
+	assert.Eventually(t, func() bool { return cv.Waiters() == 3 }, time.Second, time.Millisecond)
+	cancel()
+	assert.False(t, <-done)
+	assert.Equal(t, 2, cv.Waiters())
+
+	assert.NoError(t, cv.Broadcast())
+	<-woken
+	<-woken
+	assert.Equal(t, 0, cv.Waiters())
+}
+
+func TestCondVar_SignalN(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	woken := parkWaiters(t, cv, 5)
+	n, err := cv.SignalN(3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	for i := 0; i < 3; i++ {
+		<-woken
+	}
+	assert.Equal(t, 2, cv.Waiters())
+
+	n, err = cv.SignalN(0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Asking for more than are waiting wakes only those present.
+	n, err = cv.SignalN(10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	<-woken
+	<-woken
+
+	clone := cv.Clone()
+	clone.Drop()
+	n, err = clone.SignalN(1)
+	assert.ErrorIs(t, err, ErrClosed)
+	assert.Equal(t, 0, n)
+}
+
+func TestCondVar_DefaultOrder(t *testing.T) {
+	cv := NewCondVar()
+	defer cv.Drop()
+
+	const waiters = 5
+	woken := parkWaiters(t, cv, waiters)
+	for i := 0; i < waiters; i++ {
+		assert.NoError(t, cv.Signal())
+		assert.Equal(t, i, <-woken, "longest-waiting goroutine is woken first by default")
+	}
+}
+
+func TestCondVar_LIFO(t *testing.T) {
+	cv := NewCondVar(WithLIFO())
+	defer cv.Drop()
+
+	const waiters = 5
+	woken := parkWaiters(t, cv, waiters)
+	for i := waiters - 1; i >= 0; i-- {
+		assert.NoError(t, cv.Signal())
+		assert.Equal(t, i, <-woken, "most recent waiter must be woken first")
+	}
+}
+
+func TestNewWithLocker_FIFO(t *testing.T) {
+	var mu sync.Mutex
+	cv := NewWithLocker(&mu)
+	defer cv.Drop()
+
+	woken := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(id int) {
+			mu.Lock()
+			defer mu.Unlock()
+			if cv.Wait() == nil {
+				woken <- id
+			}
+		}(i)
+		assert.Eventually(t, func() bool { return cv.Waiters() == i+1 }, time.Second, time.Millisecond)
+	}
+	for i := 0; i < 3; i++ {
+		n, err := cv.SignalN(1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		assert.Equal(t, i, <-woken)
+	}
+}
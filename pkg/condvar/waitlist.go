@@ -0,0 +1,75 @@
+package condvar
+
+import (
+	"container/list"
+	"sync"
+)
+
+// waiter is a goroutine parked on a CondVar. ready is closed when the waiter
+// is signaled; elem is nil once the waiter has left the wait list.
+type waiter struct {
+	ready chan struct{}
+	elem  *list.Element
+}
+
+// waitList is the set of goroutines parked on a CondVar. It has its own mutex,
+// separate from the lock exposed through Lock/Unlock, so Signal and Broadcast
+// may be called with or without that lock held.
+type waitList struct {
+	mu      sync.Mutex
+	waiters list.List
+	lifo    bool // wake the most recently arrived goroutine first
+}
+
+// enqueue registers a new waiter. Registering before releasing the caller's
+// lock is what makes a check-then-wait sequence immune to lost wakeups.
+func (l *waitList) enqueue() *waiter {
+	w := &waiter{ready: make(chan struct{})}
+	l.mu.Lock()
+	w.elem = l.waiters.PushBack(w)
+	l.mu.Unlock()
+	return w
+}
+
+// cancel removes w from the wait list. It returns false if w had already been
+// signaled, in which case the caller owns that wakeup.
+func (l *waitList) cancel(w *waiter) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if w.elem == nil {
+		return false
+	}
+	l.waiters.Remove(w.elem)
+	w.elem = nil
+	return true
+}
+
+// len returns the number of parked waiters.
+func (l *waitList) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiters.Len()
+}
+
+// signal wakes up to n waiters and returns how many were woken. n < 0 wakes
+// every waiter. Waiters are woken in arrival order, like sync.Cond, unless
+// lifo is set.
+func (l *waitList) signal(n int) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	woken := 0
+	for n != woken {
+		e := l.waiters.Front()
+		if l.lifo {
+			e = l.waiters.Back()
+		}
+		if e == nil {
+			break
+		}
+		w := l.waiters.Remove(e).(*waiter)
+		w.elem = nil
+		close(w.ready)
+		woken++
+	}
+	return woken
+}
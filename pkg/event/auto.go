@@ -0,0 +1,152 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Auto is an auto-reset event. Each Set releases exactly one waiter; the event
+// resets itself as soon as that waiter has been released. If nobody is
+// waiting, the event stays set until the next waiter arrives.
+//
+// An Auto is a handle to shared state; each handle must be dropped exactly
+// once.
+type Auto struct {
+	shared  *autoState
+	dropped atomic.Bool
+}
+
+// autoState is the state shared by all handles of an Auto event.
+type autoState struct {
+	refs
+	// signal holds a token while the event is set. Receiving the token
+	// releases one waiter and resets the event in a single step.
+	signal chan struct{}
+}
+
+// NewAuto creates a new auto-reset event, initially set if set is true.
+func NewAuto(set bool) *Auto {
+	s := &autoState{signal: make(chan struct{}, 1)}
+	s.init()
+	if set {
+		s.signal <- struct{}{}
+	}
+	return &Auto{shared: s}
+}
+
+// handle returns the shared state if e is a live handle, or nil otherwise.
+func (e *Auto) handle() *autoState {
+	if e == nil || e.shared == nil || e.dropped.Load() {
+		return nil
+	}
+	return e.shared
+}
+
+// Clone creates a new handle to the event, incrementing the reference count.
+// It returns nil if e has been dropped.
+func (e *Auto) Clone() *Auto {
+	s := e.handle()
+	if s == nil || !s.acquire() {
+		return nil
+	}
+	return &Auto{shared: s}
+}
+
+// Drop releases this handle and decrements the reference count. Dropping the
+// last handle closes the event and wakes every waiter with ErrClosed.
+// Dropping the same handle more than once has no effect.
+//
+// Returns true if this was the last reference.
+func (e *Auto) Drop() bool {
+	if e == nil || e.shared == nil || !e.dropped.CompareAndSwap(false, true) {
+		return false
+	}
+	return e.shared.release()
+}
+
+// RefCount returns the number of live handles to the event.
+func (e *Auto) RefCount() int64 {
+	if e == nil || e.shared == nil {
+		return 0
+	}
+	return e.shared.count.Load()
+}
+
+// IsValid returns true if this handle has not been dropped.
+func (e *Auto) IsValid() bool {
+	return e.handle() != nil
+}
+
+// Set sets the event, releasing exactly one current or future waiter. Setting
+// an event that is already set has no effect, so several calls to Set with
+// nobody waiting release a single waiter. It returns ErrClosed if e has been
+// dropped.
+func (e *Auto) Set() error {
+	s := e.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	select {
+	case s.signal <- struct{}{}:
+	default: // already set
+	}
+	return nil
+}
+
+// Reset clears the event without releasing a waiter. It returns ErrClosed if
+// e has been dropped.
+func (e *Auto) Reset() error {
+	s := e.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	select {
+	case <-s.signal:
+	default: // not set
+	}
+	return nil
+}
+
+// IsSet returns true if the event is currently set.
+func (e *Auto) IsSet() bool {
+	s := e.handle()
+	return s != nil && len(s.signal) > 0
+}
+
+// Done returns a channel that delivers a value when the event is set, for use
+// in a select statement. Receiving from it releases this goroutine and resets
+// the event, exactly like a successful Wait; if several goroutines select on
+// Done, only one of them receives per Set. Done returns nil, which blocks
+// forever in a select, if e has been dropped.
+func (e *Auto) Done() <-chan struct{} {
+	s := e.handle()
+	if s == nil {
+		return nil
+	}
+	return s.signal
+}
+
+// Wait blocks until the event is set or ctx is done, and resets the event
+// when it returns nil. It returns ctx.Err() if ctx is done first, or
+// ErrClosed if e has been dropped or the last handle is dropped while
+// waiting.
+func (e *Auto) Wait(ctx context.Context) error {
+	s := e.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	select {
+	case <-s.signal:
+		return nil
+	case <-s.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// String returns a string representation of the event.
+func (e *Auto) String() string {
+	return fmt.Sprintf("Auto{set=%v, refCount=%d}", e.IsSet(), e.RefCount())
+}
@@ -0,0 +1,154 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuto(t *testing.T) {
+	e := NewAuto(false)
+	require.False(t, e.IsSet())
+	require.Equal(t, "Auto{set=false, refCount=1}", e.String())
+
+	set := NewAuto(true)
+	require.True(t, set.IsSet())
+	require.NoError(t, set.Wait(context.Background()))
+	require.False(t, set.IsSet(), "a successful Wait resets the event")
+}
+
+func TestAuto_SetReleasesOneWaiter(t *testing.T) {
+	e := NewAuto(false)
+	defer e.Drop()
+
+	const waiters = 5
+	var released atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if e.Wait(ctx) == nil {
+				released.Add(1)
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, e.Set())
+		require.Eventually(t, func() bool { return released.Load() == int32(i) }, time.Second, time.Millisecond)
+		// No further waiter may pass without another Set.
+		time.Sleep(5 * time.Millisecond)
+		require.Equal(t, int32(i), released.Load())
+	}
+
+	cancel()
+	wg.Wait()
+	require.Equal(t, int32(3), released.Load())
+}
+
+func TestAuto_SetWithoutWaiters(t *testing.T) {
+	e := NewAuto(false)
+	defer e.Drop()
+
+	// Several Sets with nobody waiting release a single waiter.
+	require.NoError(t, e.Set())
+	require.NoError(t, e.Set())
+	require.True(t, e.IsSet())
+	require.NoError(t, e.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestAuto_Reset(t *testing.T) {
+	e := NewAuto(true)
+	defer e.Drop()
+
+	require.NoError(t, e.Reset())
+	require.False(t, e.IsSet())
+	require.NoError(t, e.Reset())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded)
+}
+
+func TestAuto_DoneInSelect(t *testing.T) {
+	e := NewAuto(false)
+	defer e.Drop()
+
+	require.NoError(t, e.Set())
+	select {
+	case <-e.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done did not deliver after Set")
+	}
+	require.False(t, e.IsSet(), "receiving from Done resets the event")
+}
+
+func TestAuto_CloneAndDrop(t *testing.T) {
+	e := NewAuto(false)
+	clone := e.Clone()
+	require.NotSame(t, e, clone)
+	require.Equal(t, int64(2), e.RefCount())
+
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- clone.Wait(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	require.False(t, e.Drop())
+	require.False(t, e.Drop())
+	require.Equal(t, int64(1), clone.RefCount())
+	require.Nil(t, e.Clone())
+	require.ErrorIs(t, e.Set(), ErrClosed)
+	require.ErrorIs(t, e.Reset(), ErrClosed)
+	require.ErrorIs(t, e.Wait(context.Background()), ErrClosed)
+	require.Nil(t, e.Done())
+
+	require.True(t, clone.Drop())
+	require.ErrorIs(t, <-waitErr, ErrClosed)
+	require.Equal(t, int64(0), clone.RefCount())
+}
+
+func TestAuto_ConcurrentSetWait(t *testing.T) {
+	e := NewAuto(false)
+	defer e.Drop()
+
+	const (
+		waiters = 10
+		rounds  = 50
+	)
+	var wg sync.WaitGroup
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				require.NoError(t, e.Wait(context.Background()))
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			_ = e.Set()
+		}
+	}
+}
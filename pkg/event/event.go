@@ -0,0 +1,67 @@
+// Package event provides manual-reset and auto-reset events for gating
+// goroutines, replacing the common pattern of a CondVar plus a boolean guarded
+// by a mutex.
+//
+// A Manual event stays set, releasing every waiter, until it is reset. An Auto
+// event releases exactly one waiter per Set and then resets itself. Both can be
+// waited on with a context or used in a select statement through Done.
+//
+// Like condvar.CondVar, events are reference counted: every Clone returns a
+// distinct handle, each handle must be dropped exactly once, and dropping the
+// last handle closes the event, waking current waiters with ErrClosed.
+//
+// Example usage:
+//
+//	ready := event.NewManual(false)
+//	go func() {
+//		load()
+//		ready.Set()
+//	}()
+//	if err := ready.Wait(ctx); err != nil {
+//		return err
+//	}
+package event
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when using a dropped event handle, or by waits that
+// were still in progress when the last handle was dropped.
+var ErrClosed = errors.New("event: event has been dropped")
+
+// refs is the reference count shared by all handles of an event.
+type refs struct {
+	count  atomic.Int64
+	closed chan struct{} // closed when the last handle is dropped
+}
+
+// init sets up the reference count of a new event with one handle.
+func (r *refs) init() {
+	r.closed = make(chan struct{})
+	r.count.Store(1)
+}
+
+// acquire adds a reference unless the event has already been closed.
+func (r *refs) acquire() bool {
+	for {
+		current := r.count.Load()
+		if current <= 0 {
+			return false
+		}
+		if r.count.CompareAndSwap(current, current+1) {
+			return true
+		}
+	}
+}
+
+// release drops a reference and closes the event when it was the last one.
+// It returns true in that case.
+func (r *refs) release() bool {
+	if r.count.Add(-1) != 0 {
+		return false
+	}
+	close(r.closed)
+	return true
+}
@@ -0,0 +1,168 @@
+package event
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Manual is a manual-reset event. Once set it releases every current and
+// future waiter until Reset is called.
+//
+// A Manual is a handle to shared state; each handle must be dropped exactly
+// once.
+type Manual struct {
+	shared  *manualState
+	dropped atomic.Bool
+}
+
+// manualState is the state shared by all handles of a Manual event.
+type manualState struct {
+	refs
+	mu  sync.Mutex
+	set bool
+	ch  chan struct{} // closed while the event is set
+}
+
+// NewManual creates a new manual-reset event, initially set if set is true.
+func NewManual(set bool) *Manual {
+	s := &manualState{ch: make(chan struct{})}
+	s.init()
+	if set {
+		s.set = true
+		close(s.ch)
+	}
+	return &Manual{shared: s}
+}
+
+// handle returns the shared state if e is a live handle, or nil otherwise.
+func (e *Manual) handle() *manualState {
+	if e == nil || e.shared == nil || e.dropped.Load() {
+		return nil
+	}
+	return e.shared
+}
+
+// Clone creates a new handle to the event, incrementing the reference count.
+// It returns nil if e has been dropped.
+func (e *Manual) Clone() *Manual {
+	s := e.handle()
+	if s == nil || !s.acquire() {
+		return nil
+	}
+	return &Manual{shared: s}
+}
+
+// Drop releases this handle and decrements the reference count. Dropping the
+// last handle closes the event and wakes every waiter with ErrClosed.
+// Dropping the same handle more than once has no effect.
+//
+// Returns true if this was the last reference.
+func (e *Manual) Drop() bool {
+	if e == nil || e.shared == nil || !e.dropped.CompareAndSwap(false, true) {
+		return false
+	}
+	return e.shared.release()
+}
+
+// RefCount returns the number of live handles to the event.
+func (e *Manual) RefCount() int64 {
+	if e == nil || e.shared == nil {
+		return 0
+	}
+	return e.shared.count.Load()
+}
+
+// IsValid returns true if this handle has not been dropped.
+func (e *Manual) IsValid() bool {
+	return e.handle() != nil
+}
+
+// Set sets the event, releasing all current and future waiters until Reset.
+// Setting an event that is already set has no effect. It returns ErrClosed if
+// e has been dropped.
+func (e *Manual) Set() error {
+	s := e.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.set {
+		s.set = true
+		close(s.ch)
+	}
+	return nil
+}
+
+// Reset clears the event, so that subsequent waiters block until the next Set.
+// Resetting an event that is not set has no effect. It returns ErrClosed if e
+// has been dropped.
+func (e *Manual) Reset() error {
+	s := e.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.set {
+		s.set = false
+		s.ch = make(chan struct{})
+	}
+	return nil
+}
+
+// IsSet returns true if the event is currently set.
+func (e *Manual) IsSet() bool {
+	s := e.handle()
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set
+}
+
+// Done returns a channel that is closed when the event is set, for use in a
+// select statement. A channel obtained before Reset stays closed; call Done
+// again after Reset to wait for the next Set. Done returns nil, which blocks
+// forever in a select, if e has been dropped.
+func (e *Manual) Done() <-chan struct{} {
+	s := e.handle()
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ch
+}
+
+// Wait blocks until the event is set or ctx is done. It returns nil if the
+// event is set, ctx.Err() if ctx is done first, or ErrClosed if e has been
+// dropped or the last handle is dropped while waiting.
+//
+// Example:
+//
+//	if err := ready.Wait(ctx); err != nil {
+//		return err
+//	}
+func (e *Manual) Wait(ctx context.Context) error {
+	done := e.Done()
+	if done == nil {
+		return ErrClosed
+	}
+	select {
+	case <-done:
+		return nil
+	case <-e.shared.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// String returns a string representation of the event.
+func (e *Manual) String() string {
+	return fmt.Sprintf("Manual{set=%v, refCount=%d}", e.IsSet(), e.RefCount())
+}
@@ -0,0 +1,150 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewManual(t *testing.T) {
+	e := NewManual(false)
+	require.False(t, e.IsSet())
+	require.Equal(t, int64(1), e.RefCount())
+	require.Equal(t, "Manual{set=false, refCount=1}", e.String())
+
+	set := NewManual(true)
+	require.True(t, set.IsSet())
+	require.NoError(t, set.Wait(context.Background()))
+}
+
+func TestManual_SetReleasesAllWaiters(t *testing.T) {
+	e := NewManual(false)
+	defer e.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, e.Wait(context.Background()))
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, e.Set())
+	wg.Wait()
+
+	// The event stays set for later waiters.
+	require.True(t, e.IsSet())
+	require.NoError(t, e.Wait(context.Background()))
+	require.NoError(t, e.Set())
+}
+
+func TestManual_Reset(t *testing.T) {
+	e := NewManual(true)
+	defer e.Drop()
+
+	before := e.Done()
+	require.NoError(t, e.Reset())
+	require.False(t, e.IsSet())
+	require.NoError(t, e.Reset())
+
+	// A channel from before Reset stays closed; a new one waits for Set.
+	<-before
+	after := e.Done()
+	select {
+	case <-after:
+		t.Fatal("Done must not be ready after Reset")
+	default:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, e.Wait(ctx), context.DeadlineExceeded)
+
+	require.NoError(t, e.Set())
+	<-after
+}
+
+func TestManual_DoneInSelect(t *testing.T) {
+	e := NewManual(false)
+	defer e.Drop()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = e.Set()
+	}()
+	select {
+	case <-e.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done was not closed by Set")
+	}
+}
+
+func TestManual_CloneAndDrop(t *testing.T) {
+	e := NewManual(false)
+	clone := e.Clone()
+	require.NotSame(t, e, clone)
+	require.Equal(t, int64(2), e.RefCount())
+
+	// Waiters on a live handle are woken with ErrClosed by the last Drop.
+	waitErr := make(chan error, 1)
+	go func() { waitErr <- clone.Wait(context.Background()) }()
+	time.Sleep(10 * time.Millisecond)
+
+	require.False(t, e.Drop())
+	require.False(t, e.Drop())
+	require.Equal(t, int64(1), clone.RefCount())
+	require.False(t, e.IsValid())
+	require.Nil(t, e.Clone())
+	require.ErrorIs(t, e.Set(), ErrClosed)
+	require.ErrorIs(t, e.Reset(), ErrClosed)
+	require.ErrorIs(t, e.Wait(context.Background()), ErrClosed)
+	require.Nil(t, e.Done())
+
+	require.True(t, clone.Drop())
+	require.ErrorIs(t, <-waitErr, ErrClosed)
+	require.Equal(t, int64(0), clone.RefCount())
+}
+
+func TestManual_Nil(t *testing.T) {
+	var e *Manual
+	require.Nil(t, e.Clone())
+	require.False(t, e.Drop())
+	require.False(t, e.IsSet())
+	require.ErrorIs(t, e.Set(), ErrClosed)
+	require.ErrorIs(t, e.Wait(context.Background()), ErrClosed)
+}
+
+func TestManual_ConcurrentSetReset(t *testing.T) {
+	e := NewManual(false)
+	defer e.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = e.Set()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = e.Reset()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+				_ = e.Wait(ctx)
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,120 @@
+// Package exchange provides an Exchanger, a rendezvous point where pairs of
+// goroutines swap values.
+//
+// Exchanger is modeled on Java's Exchanger. Each call to Exchange waits for a
+// partner and returns the partner's value. Goroutines are paired two at a
+// time in the order they arrive, which makes an Exchanger a natural fit for
+// double buffering: a producer fills a buffer and swaps it for the empty one
+// the consumer has just drained.
+//
+// Example usage:
+//
+//	var ex exchange.Exchanger[[]byte]
+//	go func() { // producer
+//		buf := make([]byte, 0, size)
+//		for {
+//			buf = fill(buf[:0])
+//			buf, _ = ex.Exchange(ctx, buf)
+//		}
+//	}()
+//	buf := make([]byte, 0, size)
+//	for { // consumer
+//		buf, _ = ex.Exchange(ctx, buf)
+//		drain(buf)
+//	}
+package exchange
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Exchanger is a synchronization point at which goroutines pair up and swap
+// values. The zero value is ready to use. An Exchanger must not be copied
+// after first use.
+type Exchanger[T any] struct {
+	mu      sync.Mutex
+	waiting *offer[T] // goroutine waiting for a partner, if any
+}
+
+// offer is the value of a goroutine waiting for a partner. The partner sends
+// its own value on reply, which is buffered so that it never blocks.
+type offer[T any] struct {
+	value T
+	reply chan T
+}
+
+// NewExchanger creates a new Exchanger.
+func NewExchanger[T any]() *Exchanger[T] {
+	return &Exchanger[T]{}
+}
+
+// Exchange waits for another goroutine to arrive at the exchange point, gives
+// it v, and returns the value the other goroutine passed in. It returns the
+// zero value and ctx.Err() if ctx is done before a partner arrives.
+//
+// Once a partner has taken v the exchange is complete: Exchange then returns
+// the partner's value even if ctx is done, so that no value is lost.
+//
+// Example:
+//
+//	theirs, err := ex.Exchange(ctx, mine)
+//	if err != nil {
+//		return err
+//	}
+func (e *Exchanger[T]) Exchange(ctx context.Context, v T) (T, error) {
+	e.mu.Lock()
+	if o := e.waiting; o != nil {
+		// Pair with the waiting goroutine.
+		e.waiting = nil
+		e.mu.Unlock()
+		o.reply <- v
+		return o.value, nil
+	}
+	var zero T
+	if err := ctx.Err(); err != nil {
+		e.mu.Unlock()
+		return zero, err
+	}
+	o := &offer[T]{value: v, reply: make(chan T, 1)}
+	e.waiting = o
+	e.mu.Unlock()
+
+	select {
+	case theirs := <-o.reply:
+		return theirs, nil
+	case <-ctx.Done():
+		e.mu.Lock()
+		if e.waiting == o {
+			// Nobody took our offer; withdraw it.
+			e.waiting = nil
+			e.mu.Unlock()
+			return zero, ctx.Err()
+		}
+		e.mu.Unlock()
+		// A partner took our value and is about to reply.
+		return <-o.reply, nil
+	}
+}
+
+// ExchangeTimeout is like Exchange but gives up after d, returning
+// context.DeadlineExceeded.
+func (e *Exchanger[T]) ExchangeTimeout(v T, d time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	return e.Exchange(ctx, v)
+}
+
+// IsWaiting returns true if a goroutine is waiting for a partner.
+func (e *Exchanger[T]) IsWaiting() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.waiting != nil
+}
+
+// String returns a string representation of the exchanger.
+func (e *Exchanger[T]) String() string {
+	return fmt.Sprintf("Exchanger{waiting=%v}", e.IsWaiting())
+}
@@ -0,0 +1,156 @@
+package exchange
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchanger_Exchange(t *testing.T) {
+	ex := NewExchanger[string]()
+	require.Equal(t, "Exchanger{waiting=false}", ex.String())
+
+	got := make(chan string, 1)
+	go func() {
+		v, err := ex.Exchange(context.Background(), "ping")
+		require.NoError(t, err)
+		got <- v
+	}()
+	require.Eventually(t, ex.IsWaiting, time.Second, time.Millisecond)
+	require.Equal(t, "Exchanger{waiting=true}", ex.String())
+
+	v, err := ex.Exchange(context.Background(), "pong")
+	require.NoError(t, err)
+	require.Equal(t, "ping", v)
+	require.Equal(t, "pong", <-got)
+	require.False(t, ex.IsWaiting())
+}
+
+func TestExchanger_ZeroValue(t *testing.T) {
+	var ex Exchanger[int]
+	done := make(chan int, 1)
+	go func() {
+		v, _ := ex.Exchange(context.Background(), 1)
+		done <- v
+	}()
+	v, err := ex.Exchange(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, 1, v)
+	require.Equal(t, 2, <-done)
+}
+
+func TestExchanger_Timeout(t *testing.T) {
+	ex := NewExchanger[int]()
+
+	v, err := ex.ExchangeTimeout(1, 10*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, v)
+	// The withdrawn offer is not handed to the next goroutine.
+	require.False(t, ex.IsWaiting())
+
+	_, err = ex.ExchangeTimeout(2, 10*time.Millisecond)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestExchanger_Canceled(t *testing.T) {
+	ex := NewExchanger[int]()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := ex.Exchange(ctx, 1)
+	require.ErrorIs(t, err, context.Canceled)
+	require.False(t, ex.IsWaiting())
+
+	ctx, cancel = context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := ex.Exchange(ctx, 1)
+		errCh <- err
+	}()
+	require.Eventually(t, ex.IsWaiting, time.Second, time.Millisecond)
+	cancel()
+	require.ErrorIs(t, <-errCh, context.Canceled)
+	require.False(t, ex.IsWaiting())
+}
+
+func TestExchanger_DoubleBuffer(t *testing.T) {
+	ex := NewExchanger[[]int]()
+	const rounds = 100
+
+	go func() {
+		buf := make([]int, 0, 1)
+		for i := 0; i < rounds; i++ {
+			buf = append(buf[:0], i)
+			var err error
+			buf, err = ex.Exchange(context.Background(), buf)
+			require.NoError(t, err)
+		}
+	}()
+
+	buf := make([]int, 0, 1)
+	for i := 0; i < rounds; i++ {
+		var err error
+		buf, err = ex.Exchange(context.Background(), buf)
+		require.NoError(t, err)
+		require.Equal(t, []int{i}, buf)
+	}
+}
+
+func TestExchanger_ConcurrentPairs(t *testing.T) {
+	const goroutines = 200 // even, so everybody finds a partner
+	ex := NewExchanger[int]()
+
+	got := make([]int, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := ex.ExchangeTimeout(i, 10*time.Second)
+			require.NoError(t, err)
+			got[i] = v
+		}(i)
+	}
+	wg.Wait()
+
+	// Exchanges form a perfect matching: whoever i received a value from
+	// received i's value in return.
+	for i, partner := range got {
+		require.NotEqual(t, i, partner)
+		require.Equal(t, i, got[partner], "goroutine %d paired with %d", i, partner)
+	}
+}
+
+func TestExchanger_ConcurrentCancellation(t *testing.T) {
+	// Goroutines give up at random points while others keep exchanging.
+	// Every value handed over must be returned to exactly one partner.
+	const goroutines = 100
+	ex := NewExchanger[int]()
+
+	got := make([]int, goroutines)
+	ok := make([]bool, goroutines)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := ex.ExchangeTimeout(i, time.Duration(i%5)*time.Millisecond)
+			if err == nil {
+				got[i], ok[i] = v, true
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for i := range got {
+		if !ok[i] {
+			continue
+		}
+		partner := got[i]
+		require.True(t, ok[partner], "partner %d of %d lost the exchange", partner, i)
+		require.Equal(t, i, got[partner])
+	}
+}
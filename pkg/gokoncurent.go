@@ -114,19 +114,19 @@ func NewArcMutex[T any](value T) *ArcMutex[T] {
 	return &ArcMutex[T]{ArcMutex: arcmutex.NewArcMutex(value)}
 }
 
-// NewRWArcMutex creates a new RWArcMutex[T] with the given value.
-func NewRWArcMutex[T any](value T) *RWArcMutex[T] {
-	return &RWArcMutex[T]{RWArcMutex: rwarcmutex.NewRWArcMutex(value)}
+// NewRWArcMutex creates a new RWArcMutex[T] with the given value and options.
+func NewRWArcMutex[T any](value T, opts ...rwarcmutex.Option) *RWArcMutex[T] {
+	return &RWArcMutex[T]{RWArcMutex: rwarcmutex.NewRWArcMutex(value, opts...)}
 }
 
-// NewCondVar creates a new CondVar for goroutine coordination.
-func NewCondVar() *CondVar {
-	return &CondVar{CondVar: condvar.NewCondVar()}
+// NewCondVar creates a new CondVar for goroutine coordination with the given options.
+func NewCondVar(opts ...condvar.Option) *CondVar {
+	return &CondVar{CondVar: condvar.NewCondVar(opts...)}
 }
 
-// NewBarrier creates a new Barrier for synchronizing multiple goroutines.
-func NewBarrier(n int) *Barrier {
-	return &Barrier{Barrier: barrier.NewBarrier(n)}
+// NewBarrier creates a new Barrier for synchronizing multiple goroutines with the given options.
+func NewBarrier(n int, opts ...barrier.Option) *Barrier {
+	return &Barrier{Barrier: barrier.NewBarrier(n, opts...)}
 }
 
 // NewOnceCell creates a new OnceCell[T] for lazy initialization.
@@ -0,0 +1,206 @@
+// Package latch provides a CountDownLatch, a one-shot gate that opens once a
+// fixed number of events have happened.
+//
+// Unlike a barrier, the goroutines counting down never block: they only record
+// that an event happened. Goroutines waiting on the latch are released once the
+// count reaches zero, after which the latch stays open for good. Waits can be
+// cancelled with a context, and Done exposes the latch to select statements.
+//
+// Like the other primitives in this module, latches are reference counted:
+// every Clone returns a distinct handle, each handle must be dropped exactly
+// once, and dropping the last handle closes the latch, waking current waiters
+// with ErrClosed.
+//
+// Example usage:
+//
+//	started := latch.NewCountDownLatch(workers)
+//	for i := 0; i < workers; i++ {
+//		go func() {
+//			setup()
+//			started.CountDown()
+//			work()
+//		}()
+//	}
+//	if err := started.Await(ctx); err != nil {
+//		return err
+//	}
+package latch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// ErrClosed is returned when using a dropped latch handle, or by waits that
+// were still in progress when the last handle was dropped.
+var ErrClosed = errors.New("latch: latch has been dropped")
+
+// CountDownLatch is a one-shot gate that opens once CountDown has been called
+// a fixed number of times.
+//
+// A CountDownLatch is a handle to shared state; each handle must be dropped
+// exactly once.
+type CountDownLatch struct {
+	shared  *latchState
+	dropped atomic.Bool
+}
+
+// latchState is the state shared by all handles of a CountDownLatch.
+type latchState struct {
+	count    atomic.Int64
+	done     chan struct{} // closed when count reaches zero
+	refCount atomic.Int64
+	closed   chan struct{} // closed when the last handle is dropped
+}
+
+// NewCountDownLatch creates a new latch that opens after count calls to
+// CountDown. A latch created with a count of zero is already open.
+// It panics if count is negative.
+func NewCountDownLatch(count int) *CountDownLatch {
+	if count < 0 {
+		panic("latch: count must not be negative")
+	}
+	s := &latchState{
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	s.count.Store(int64(count))
+	s.refCount.Store(1)
+	if count == 0 {
+		close(s.done)
+	}
+	return &CountDownLatch{shared: s}
+}
+
+// handle returns the shared state if l is a live handle, or nil otherwise.
+func (l *CountDownLatch) handle() *latchState {
+	if l == nil || l.shared == nil || l.dropped.Load() {
+		return nil
+	}
+	return l.shared
+}
+
+// Clone creates a new handle to the latch, incrementing the reference count.
+// It returns nil if l has been dropped.
+func (l *CountDownLatch) Clone() *CountDownLatch {
+	s := l.handle()
+	if s == nil {
+		return nil
+	}
+	for {
+		current := s.refCount.Load()
+		if current <= 0 {
+			return nil
+		}
+		if s.refCount.CompareAndSwap(current, current+1) {
+			return &CountDownLatch{shared: s}
+		}
+	}
+}
+
+// Drop releases this handle and decrements the reference count. Dropping the
+// last handle closes the latch and wakes every waiter with ErrClosed.
+// Dropping the same handle more than once has no effect.
+//
+// Returns true if this was the last reference.
+func (l *CountDownLatch) Drop() bool {
+	if l == nil || l.shared == nil || !l.dropped.CompareAndSwap(false, true) {
+		return false
+	}
+	if l.shared.refCount.Add(-1) != 0 {
+		return false
+	}
+	close(l.shared.closed)
+	return true
+}
+
+// RefCount returns the number of live handles to the latch.
+func (l *CountDownLatch) RefCount() int64 {
+	if l == nil || l.shared == nil {
+		return 0
+	}
+	return l.shared.refCount.Load()
+}
+
+// IsValid returns true if this handle has not been dropped.
+func (l *CountDownLatch) IsValid() bool {
+	return l.handle() != nil
+}
+
+// CountDown decrements the count, opening the latch when it reaches zero.
+// It never blocks, and counting down an open latch has no effect.
+// It returns ErrClosed if l has been dropped.
+func (l *CountDownLatch) CountDown() error {
+	s := l.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	for {
+		current := s.count.Load()
+		if current == 0 {
+			return nil
+		}
+		if s.count.CompareAndSwap(current, current-1) {
+			if current == 1 {
+				close(s.done)
+			}
+			return nil
+		}
+	}
+}
+
+// Count returns the number of CountDown calls still needed to open the latch.
+func (l *CountDownLatch) Count() int64 {
+	if l == nil || l.shared == nil {
+		return 0
+	}
+	return l.shared.count.Load()
+}
+
+// Done returns a channel that is closed once the latch opens, for use in a
+// select statement. Done returns nil, which blocks forever in a select, if l
+// has been dropped.
+func (l *CountDownLatch) Done() <-chan struct{} {
+	s := l.handle()
+	if s == nil {
+		return nil
+	}
+	return s.done
+}
+
+// Await blocks until the latch opens or ctx is done. It returns nil once the
+// latch is open, ctx.Err() if ctx is done first, or ErrClosed if l has been
+// dropped or the last handle is dropped while waiting.
+//
+// Example:
+//
+//	if err := started.Await(ctx); err != nil {
+//		return err
+//	}
+func (l *CountDownLatch) Await(ctx context.Context) error {
+	s := l.handle()
+	if s == nil {
+		return ErrClosed
+	}
+	// An open latch wins over a done context or a dropped latch.
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	select {
+	case <-s.done:
+		return nil
+	case <-s.closed:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// String returns a string representation of the latch.
+func (l *CountDownLatch) String() string {
+	return fmt.Sprintf("CountDownLatch{count=%d, refCount=%d}", l.Count(), l.RefCount())
+}
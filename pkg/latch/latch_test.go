@@ -0,0 +1,137 @@
+package latch
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCountDownLatch(t *testing.T) {
+	l := NewCountDownLatch(3)
+	defer l.Drop()
+	require.Equal(t, int64(3), l.Count())
+	require.Equal(t, int64(1), l.RefCount())
+	require.True(t, l.IsValid())
+	require.Equal(t, "CountDownLatch{count=3, refCount=1}", l.String())
+
+	open := NewCountDownLatch(0)
+	defer open.Drop()
+	require.NoError(t, open.Await(context.Background()))
+	<-open.Done()
+
+	require.Panics(t, func() { NewCountDownLatch(-1) })
+}
+
+func TestCountDownLatch_CountDown(t *testing.T) {
+	l := NewCountDownLatch(2)
+	defer l.Drop()
+
+	require.NoError(t, l.CountDown())
+	require.Equal(t, int64(1), l.Count())
+	select {
+	case <-l.Done():
+		t.Fatal("latch must not open before the count reaches zero")
+	default:
+	}
+
+	require.NoError(t, l.CountDown())
+	require.Equal(t, int64(0), l.Count())
+	<-l.Done()
+
+	// Counting down an open latch has no effect.
+	require.NoError(t, l.CountDown())
+	require.Equal(t, int64(0), l.Count())
+	require.NoError(t, l.Await(context.Background()))
+}
+
+func TestCountDownLatch_ReleasesAllWaiters(t *testing.T) {
+	const workers = 20
+	l := NewCountDownLatch(workers)
+	defer l.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, l.Await(context.Background()))
+			require.Equal(t, int64(0), l.Count())
+		}()
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			require.NoError(t, l.CountDown())
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCountDownLatch_AwaitContext(t *testing.T) {
+	l := NewCountDownLatch(1)
+	defer l.Drop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, l.Await(ctx), context.DeadlineExceeded)
+
+	// An open latch wins over a context that is already done.
+	require.NoError(t, l.CountDown())
+	require.NoError(t, l.Await(ctx))
+}
+
+func TestCountDownLatch_CloneAndDrop(t *testing.T) {
+	l := NewCountDownLatch(1)
+	clone := l.Clone()
+	require.NotNil(t, clone)
+	require.Equal(t, int64(2), l.RefCount())
+
+	// Handles share the count.
+	require.NoError(t, clone.CountDown())
+	require.NoError(t, l.Await(context.Background()))
+
+	require.False(t, clone.Drop())
+	require.False(t, clone.Drop())
+	require.False(t, clone.IsValid())
+	require.Nil(t, clone.Clone())
+	require.Nil(t, clone.Done())
+	require.ErrorIs(t, clone.CountDown(), ErrClosed)
+	require.ErrorIs(t, clone.Await(context.Background()), ErrClosed)
+
+	require.True(t, l.Drop())
+	require.Equal(t, int64(0), l.RefCount())
+}
+
+func TestCountDownLatch_DropWakesWaiters(t *testing.T) {
+	l := NewCountDownLatch(1)
+	waiter := l.Clone()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- waiter.Await(context.Background())
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	require.False(t, l.Drop())
+	select {
+	case err := <-errCh:
+		t.Fatalf("waiter returned early: %v", err)
+	default:
+	}
+	// The waiter's own handle is the last one; dropping it from another
+	// goroutine closes the latch.
+	require.True(t, waiter.Drop())
+	require.ErrorIs(t, <-errCh, ErrClosed)
+}
+
+func TestCountDownLatch_NilHandle(t *testing.T) {
+	var l *CountDownLatch
+	require.False(t, l.IsValid())
+	require.False(t, l.Drop())
+	require.Nil(t, l.Clone())
+	require.Equal(t, int64(0), l.Count())
+	require.ErrorIs(t, l.CountDown(), ErrClosed)
+	require.ErrorIs(t, l.Await(context.Background()), ErrClosed)
+}
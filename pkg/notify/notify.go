@@ -0,0 +1,132 @@
+// Package notify provides a notification primitive that never loses wakeups.
+//
+// Notify is modeled on tokio's Notify. Unlike condvar.Notify, a notification
+// sent while nobody is waiting is not lost: NotifyOne stores a single permit
+// that the next call to Notified consumes immediately. This closes the startup
+// race where a producer signals before the consumer has started waiting.
+//
+// Example usage:
+//
+//	n := notify.NewNotify()
+//	go func() {
+//		prepare()
+//		n.NotifyOne() // safe even if the consumer is not waiting yet
+//	}()
+//	if err := n.Notified(ctx); err != nil {
+//		return err
+//	}
+package notify
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Notify wakes goroutines waiting for a notification. The zero value is ready
+// to use. A Notify must not be copied after first use.
+type Notify struct {
+	mu      sync.Mutex
+	permit  bool // a NotifyOne arrived while nobody was waiting
+	waiters list.List
+}
+
+// waiter is a goroutine parked in Notified. ready is closed when it is
+// notified; elem is nil once the waiter has left the wait list.
+type waiter struct {
+	ready chan struct{}
+	elem  *list.Element
+}
+
+// NewNotify creates a new Notify with no stored permit.
+func NewNotify() *Notify {
+	return &Notify{}
+}
+
+// NotifyOne wakes the goroutine that has been waiting in Notified the
+// longest. If no goroutine is waiting, a permit is stored instead and the
+// next call to Notified returns immediately. At most one permit is stored:
+// several calls to NotifyOne with nobody waiting release a single Notified.
+func (n *Notify) NotifyOne() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	e := n.waiters.Front()
+	if e == nil {
+		n.permit = true
+		return
+	}
+	n.wake(e)
+}
+
+// NotifyWaiters wakes every goroutine currently waiting in Notified. It does
+// not store a permit, so goroutines that call Notified afterwards wait for the
+// next notification.
+func (n *Notify) NotifyWaiters() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for e := n.waiters.Front(); e != nil; e = n.waiters.Front() {
+		n.wake(e)
+	}
+}
+
+// wake removes the waiter at e from the wait list and releases it.
+// The caller must hold n.mu.
+func (n *Notify) wake(e *list.Element) {
+	w := n.waiters.Remove(e).(*waiter)
+	w.elem = nil
+	close(w.ready)
+}
+
+// Notified waits for a notification. If a permit is stored it is consumed and
+// Notified returns nil immediately; otherwise it waits for NotifyOne or
+// NotifyWaiters.
+//
+// If ctx is done first, Notified returns ctx.Err() and leaves the wait list
+// without consuming a notification meant for another goroutine. If the
+// notification and the cancellation race, the notification wins and
+// Notified returns nil.
+func (n *Notify) Notified(ctx context.Context) error {
+	n.mu.Lock()
+	if n.permit {
+		n.permit = false
+		n.mu.Unlock()
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		n.mu.Unlock()
+		return err
+	}
+	w := &waiter{ready: make(chan struct{})}
+	w.elem = n.waiters.PushBack(w)
+	n.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if w.elem == nil {
+			// Notified concurrently with the cancellation.
+			return nil
+		}
+		n.waiters.Remove(w.elem)
+		w.elem = nil
+		return ctx.Err()
+	}
+}
+
+// Waiters returns the number of goroutines currently waiting in Notified.
+func (n *Notify) Waiters() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.waiters.Len()
+}
+
+// String returns a string representation of the Notify.
+func (n *Notify) String() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return fmt.Sprintf("Notify{waiters=%d, permit=%v}", n.waiters.Len(), n.permit)
+}
@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// waitForWaiters blocks until n goroutines are parked in Notified.
+func waitForWaiters(t *testing.T, n *Notify, want int) {
+	t.Helper()
+	require.Eventually(t, func() bool { return n.Waiters() == want }, time.Second, time.Millisecond)
+}
+
+func TestNotify_NotifyOneBeforeNotified(t *testing.T) {
+	n := NewNotify()
+
+	// The permit is stored because nobody is waiting yet.
+	n.NotifyOne()
+	require.Equal(t, "Notify{waiters=0, permit=true}", n.String())
+	require.NoError(t, n.Notified(context.Background()))
+
+	// The permit has been consumed.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, n.Notified(ctx), context.DeadlineExceeded)
+}
+
+func TestNotify_SinglePermit(t *testing.T) {
+	n := NewNotify()
+	n.NotifyOne()
+	n.NotifyOne()
+	n.NotifyOne()
+
+	require.NoError(t, n.Notified(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, n.Notified(ctx), context.DeadlineExceeded, "permits must not accumulate")
+}
+
+func TestNotify_ZeroValue(t *testing.T) {
+	var n Notify
+	n.NotifyOne()
+	require.NoError(t, n.Notified(context.Background()))
+}
+
+func TestNotify_NotifyOneWakesLongestWaiter(t *testing.T) {
+	n := NewNotify()
+	woken := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(id int) {
+			if n.Notified(context.Background()) == nil {
+				woken <- id
+			}
+		}(i)
+		waitForWaiters(t, n, i+1)
+	}
+
+	for i := 0; i < 3; i++ {
+		n.NotifyOne()
+		require.Equal(t, i, <-woken)
+	}
+	require.Equal(t, "Notify{waiters=0, permit=false}", n.String())
+}
+
+func TestNotify_NotifyWaiters(t *testing.T) {
+	n := NewNotify()
+
+	// With nobody waiting, NotifyWaiters does not store a permit.
+	n.NotifyWaiters()
+	require.Equal(t, "Notify{waiters=0, permit=false}", n.String())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, n.Notified(context.Background()))
+		}()
+	}
+	waitForWaiters(t, n, 5)
+	n.NotifyWaiters()
+	wg.Wait()
+
+	// A goroutine arriving after NotifyWaiters waits for the next notification.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, n.Notified(ctx), context.DeadlineExceeded)
+}
+
+func TestNotify_CancelDoesNotStealNotification(t *testing.T) {
+	n := NewNotify()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error, 1)
+	go func() { canceled <- n.Notified(ctx) }()
+	waitForWaiters(t, n, 1)
+
+	other := make(chan error, 1)
+	go func() { other <- n.Notified(context.Background()) }()
+	waitForWaiters(t, n, 2)
+
+	cancel()
+	require.ErrorIs(t, <-canceled, context.Canceled)
+	require.Equal(t, 1, n.Waiters())
+
+	n.NotifyOne()
+	require.NoError(t, <-other)
+}
+
+func TestNotify_CanceledContext(t *testing.T) {
+	n := NewNotify()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, n.Notified(ctx), context.Canceled)
+	require.Equal(t, 0, n.Waiters())
+
+	// A stored permit is consumed even if the context is already done.
+	n.NotifyOne()
+	require.NoError(t, n.Notified(ctx))
+}
+
+func TestNotify_ConcurrentProducerConsumers(t *testing.T) {
+	const (
+		consumers = 20
+		rounds    = 20
+	)
+	n := NewNotify()
+
+	var received atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				require.NoError(t, n.Notified(context.Background()))
+				received.Add(1)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			require.Equal(t, int64(consumers*rounds), received.Load())
+			return
+		default:
+			n.NotifyOne()
+		}
+	}
+}
@@ -4,22 +4,44 @@
 package oncecell
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// ErrInitPanicked is returned to callers that were waiting on an
+// initialization attempt that panicked. The cell is left empty.
+var ErrInitPanicked = errors.New("oncecell: initialization panicked")
+
 // OnceCell represents a thread-safe cell that can be written to only once,
 // but read from many times. This is inspired by Rust's OnceCell<T>.
 //
 // OnceCell[T] uses Go 1.24's atomic.Pointer[T] for efficient lock-free reads
-// after the initial write, with sync.Once ensuring exactly one write operation.
+// after the initial write. Writes go through a small state machine guarded by
+// a mutex: the cell is empty, being initialized by one attempt that other
+// callers join, or initialized for good. Unlike sync.Once, an attempt can
+// fail and leave the cell empty, and callers can stop waiting for it.
 //
 // This is useful for lazy initialization patterns where you want to compute
 // a value only once and share it across multiple goroutines.
 type OnceCell[T any] struct {
-	once  sync.Once
 	value atomic.Pointer[T]
+
+	mu      sync.Mutex
+	attempt *attempt[T] // in-flight initialization, if any
+}
+
+// attempt is an initialization shared by concurrent callers. value and err
+// are written before done is closed.
+type attempt[T any] struct {
+	done    chan struct{}
+	value   T
+	err     error
+	waiters int                // callers waiting for the attempt, guarded by mu
+	cancel  context.CancelFunc // cancels init once every waiter has left
 }
 
 // NewOnceCell creates a new empty OnceCell[T].
@@ -53,13 +75,14 @@ func (oc *OnceCell[T]) Set(value T) bool {
 		return false
 	}
 
-	var wasSet bool
-	oc.once.Do(func() {
-		oc.value.Store(&value)
-		wasSet = true
-	})
-
-	return wasSet
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	if oc.value.Load() != nil {
+		return false
+	}
+	// An attempt in flight finds the value and returns it instead of its own.
+	oc.value.Store(&value)
+	return true
 }
 
 // Get retrieves the value from the cell.
@@ -121,20 +144,16 @@ func (oc *OnceCell[T]) GetOrInit(init func() T) T {
 		return *ptr
 	}
 
-	// Slow path: initialize
-	var result T
-	oc.once.Do(func() {
-		result = init()
-		oc.value.Store(&result)
-	})
-
-	// If another goroutine initialized it first, return that value
-	if ptr := oc.value.Load(); ptr != nil {
-		return *ptr
+	// Slow path: initialize, or wait for the attempt in flight. If that
+	// attempt fails, which init never does, try again with init.
+	for {
+		value, err := oc.initialize(nil, func(context.Context) (T, error) {
+			return init(), nil
+		})
+		if err == nil {
+			return value
+		}
 	}
-
-	// This should not happen under normal circumstances
-	return result
 }
 
 // GetOrInitWith returns the value from the cell if it's initialized,
@@ -178,8 +197,8 @@ func (oc *OnceCell[T]) TryGet() (T, bool) {
 }
 
 // Reset creates a new OnceCell[T] with the same type.
-// This doesn't actually reset the current cell (which is impossible
-// due to sync.Once semantics), but returns a new empty cell.
+// This doesn't actually reset the current cell (a stored value is
+// never replaced), but returns a new empty cell.
 //
 // This method is useful when you need to restart the lazy initialization
 // process with a new cell instance.
@@ -225,6 +244,10 @@ func (oc *OnceCell[T]) ResetWithCallback(callback func(T)) *OnceCell[T] {
 // The retry strategy uses exponential backoff: initialBackoff, 2*initialBackoff, 4*initialBackoff, etc.
 // If maxRetries is 0, no retries are performed.
 //
+// If every attempt fails, the cell is left empty and the last error is
+// returned, so a later call starts over. Concurrent callers share the retry
+// loop in flight, as with GetOrTryInit.
+//
 // Example:
 //
 //	cell := NewOnceCell[string]()
@@ -240,28 +263,14 @@ func (oc *OnceCell[T]) GetOrInitWithRetry(
 	maxRetries int,
 	initialBackoff time.Duration,
 ) (T, error) {
-	if oc == nil {
+	return oc.GetOrTryInit(func() (T, error) {
 		var zero T
-		return zero, nil
-	}
-
-	// Fast path: check if already initialized
-	if ptr := oc.value.Load(); ptr != nil {
-		return *ptr, nil
-	}
-
-	// Slow path: initialize with retry
-	var result T
-	var lastErr error
-	oc.once.Do(func() {
+		var lastErr error
 		backoff := initialBackoff
 		for attempt := 0; attempt <= maxRetries; attempt++ {
 			value, err := init()
 			if err == nil {
-				result = value
-				oc.value.Store(&result)
-				lastErr = nil // clear error on success
-				return
+				return value, nil
 			}
 			lastErr = err
 			if attempt < maxRetries {
@@ -269,14 +278,194 @@ func (oc *OnceCell[T]) GetOrInitWithRetry(
 				backoff *= 2 // Exponential backoff
 			}
 		}
-		// If all retries failed, store zero value (result) but keep lastErr
-		oc.value.Store(&result)
+		return zero, lastErr
 	})
+}
 
-	ptr := oc.value.Load()
-	if ptr == nil {
+// GetOrTryInit returns the value from the cell if it's initialized,
+// otherwise initializes it with the result of the provided fallible function.
+//
+// If init returns an error, the cell is left empty and the error is returned,
+// so a later call can try again. Concurrent callers do not start attempts of
+// their own: they wait for the attempt in flight and share its value or error.
+// A successful value is stored exactly once; if the cell was initialized by
+// Set or GetOrInit in the meantime, that value wins and is returned instead.
+//
+// If init panics, the cell is left empty, the panic propagates to the caller
+// that ran init, and callers waiting on that attempt get ErrInitPanicked.
+//
+// Example:
+//
+//	cell := NewOnceCell[*sql.DB]()
+//	db, err := cell.GetOrTryInit(func() (*sql.DB, error) {
+//	    return sql.Open("postgres", dsn)
+//	})
+//	if err != nil {
+//	    return err // the next call tries to connect again
+//	}
+func (oc *OnceCell[T]) GetOrTryInit(init func() (T, error)) (T, error) {
+	if oc == nil {
 		var zero T
-		return zero, lastErr
+		return zero, nil
+	}
+
+	// Fast path: check if already initialized
+	if ptr := oc.value.Load(); ptr != nil {
+		return *ptr, nil
+	}
+
+	return oc.initialize(nil, func(context.Context) (T, error) {
+		return init()
+	})
+}
+
+// GetOrInitContext returns the value from the cell if it's initialized,
+// otherwise initializes it with the result of init, waiting at most until ctx
+// is done.
+//
+// Concurrent callers share a single attempt, as with GetOrTryInit. The first
+// caller starts init in a new goroutine, and each caller waits for it on its
+// own context: a caller whose ctx is done returns ctx.Err() while init keeps
+// running for the others. The context passed to init is independent of every
+// caller's and is cancelled once all callers have given up; a new caller
+// after that starts a fresh attempt.
+//
+// If init returns an error, the cell is left empty and every caller waiting
+// on the attempt gets the error. If init panics, the panic is recovered, the
+// cell is left empty and the waiting callers get an error wrapping
+// ErrInitPanicked.
+//
+// Example:
+//
+//	cfg, err := cell.GetOrInitContext(ctx, func(ctx context.Context) (*Config, error) {
+//	    return fetchConfig(ctx) // cancelled if every caller gives up
+//	})
+func (oc *OnceCell[T]) GetOrInitContext(
+	ctx context.Context,
+	init func(context.Context) (T, error),
+) (T, error) {
+	var zero T
+	if oc == nil {
+		return zero, nil
+	}
+
+	// Fast path: check if already initialized
+	if ptr := oc.value.Load(); ptr != nil {
+		return *ptr, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	return oc.initialize(ctx, init)
+}
+
+// initialize joins the attempt in flight, or starts a new one with init. A
+// nil ctx never gives up, and the attempt runs init in the calling goroutine
+// if it starts one, so that a panic propagates to the caller. Otherwise init
+// runs in a new goroutine and the caller stops waiting when ctx is done.
+func (oc *OnceCell[T]) initialize(ctx context.Context, init func(context.Context) (T, error)) (T, error) {
+	oc.mu.Lock()
+	if ptr := oc.value.Load(); ptr != nil {
+		oc.mu.Unlock()
+		return *ptr, nil
+	}
+	if a := oc.attempt; a != nil {
+		a.waiters++
+		oc.mu.Unlock()
+		return oc.wait(ctx, a)
+	}
+	initCtx, cancel := context.WithCancel(context.Background())
+	a := &attempt[T]{done: make(chan struct{}), waiters: 1, cancel: cancel}
+	oc.attempt = a
+	oc.mu.Unlock()
+
+	if ctx == nil {
+		a.err = ErrInitPanicked
+		defer func() {
+			// Runs on panic too, leaving ErrInitPanicked for the waiters.
+			oc.finish(a)
+		}()
+		var zero T
+		value, err := init(initCtx)
+		if err != nil {
+			a.err = err
+			return zero, err
+		}
+		a.value, a.err = value, nil
+		oc.finish(a)
+		return a.value, a.err
+	}
+
+	go func() {
+		defer oc.finish(a)
+		defer func() {
+			if r := recover(); r != nil {
+				a.err = fmt.Errorf("%w: %v", ErrInitPanicked, r)
+			}
+		}()
+		a.value, a.err = init(initCtx)
+	}()
+	return oc.wait(ctx, a)
+}
+
+// finish completes attempt a: a successful value is stored unless the cell
+// was initialized in the meantime, in which case a reports the stored value.
+// Calling finish more than once has no effect.
+func (oc *OnceCell[T]) finish(a *attempt[T]) {
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	select {
+	case <-a.done:
+		return
+	default:
+	}
+	if a.err == nil {
+		if oc.value.Load() == nil {
+			value := a.value
+			oc.value.Store(&value)
+		}
+		a.value = *oc.value.Load()
+	} else {
+		var zero T
+		a.value = zero
+	}
+	if oc.attempt == a {
+		oc.attempt = nil
+	}
+	a.cancel()
+	close(a.done)
+}
+
+// wait waits for attempt a, which the caller has joined, to finish. A nil ctx
+// never gives up. The last caller to give up cancels the attempt and detaches
+// it from the cell, so that the next caller starts afresh.
+func (oc *OnceCell[T]) wait(ctx context.Context, a *attempt[T]) (T, error) {
+	var canceled <-chan struct{}
+	if ctx != nil {
+		canceled = ctx.Done()
+	}
+	select {
+	case <-a.done:
+		return a.value, a.err
+	case <-canceled:
+	}
+
+	oc.mu.Lock()
+	defer oc.mu.Unlock()
+	select {
+	case <-a.done:
+		// The attempt finished before we could give up.
+		return a.value, a.err
+	default:
+	}
+	a.waiters--
+	if a.waiters == 0 {
+		a.cancel()
+		if oc.attempt == a {
+			oc.attempt = nil
+		}
 	}
-	return *ptr, lastErr
+	var zero T
+	return zero, ctx.Err()
 }
@@ -1,6 +1,8 @@
 package oncecell
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -666,9 +668,19 @@ func TestOnceCellGetOrInitWithRetry(t *testing.T) {
 		if value != "" {
 			t.Errorf("Expected empty value, got '%s'", value)
 		}
-		// Cell should be initialized even after failed retries
-		if !cell.IsInitialized() {
-			t.Error("Cell should be initialized even after failed retries")
+		// Cell should stay empty so that a later call can try again
+		if cell.IsInitialized() {
+			t.Error("Cell should not be initialized after failed retries")
+		}
+
+		value, err = cell.GetOrInitWithRetry(func() (string, error) {
+			return "recovered", nil
+		}, 2, 10*time.Millisecond)
+		if err != nil {
+			t.Errorf("Expected no error on a later call, got %v", err)
+		}
+		if value != "recovered" {
+			t.Errorf("Expected 'recovered', got '%s'", value)
 		}
 	})
 
@@ -758,6 +770,408 @@ func TestOnceCellGetOrInitWithRetry(t *testing.T) {
 	})
 }
 
+func TestOnceCellGetOrTryInit(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+
+		value, err := cell.GetOrTryInit(func() (int, error) {
+			return 42, nil
+		})
+		if err != nil || value != 42 {
+			t.Errorf("Expected 42 and no error, got %d and %v", value, err)
+		}
+
+		value, err = cell.GetOrTryInit(func() (int, error) {
+			t.Error("Init function should not be called for initialized cell")
+			return 0, nil
+		})
+		if err != nil || value != 42 {
+			t.Errorf("Expected 42 and no error, got %d and %v", value, err)
+		}
+		if cell.Set(100) {
+			t.Error("Set should fail after GetOrTryInit stored a value")
+		}
+	})
+
+	t.Run("failure leaves the cell empty", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		errFailed := errors.New("failed")
+
+		value, err := cell.GetOrTryInit(func() (int, error) {
+			return 7, errFailed
+		})
+		if !errors.Is(err, errFailed) {
+			t.Errorf("Expected errFailed, got %v", err)
+		}
+		if value != 0 {
+			t.Errorf("Expected zero value on failure, got %d", value)
+		}
+		if cell.IsInitialized() {
+			t.Error("Cell should stay empty after a failed attempt")
+		}
+
+		value, err = cell.GetOrTryInit(func() (int, error) {
+			return 8, nil
+		})
+		if err != nil || value != 8 {
+			t.Errorf("Expected 8 and no error, got %d and %v", value, err)
+		}
+	})
+
+	t.Run("concurrent callers share the attempt", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		errFailed := errors.New("failed")
+		release := make(chan struct{})
+		var calls atomic.Int32
+
+		const numGoroutines = 10
+		var wg sync.WaitGroup
+		errs := make([]error, numGoroutines)
+		wg.Add(numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			go func(id int) {
+				defer wg.Done()
+				_, errs[id] = cell.GetOrTryInit(func() (int, error) {
+					calls.Add(1)
+					<-release
+					return 0, errFailed
+				})
+			}(i)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		// Late starters may begin a new attempt once the first has failed,
+		// but every caller sees a failure and nobody runs twice at once.
+		if calls.Load() == 0 || calls.Load() > numGoroutines {
+			t.Errorf("Unexpected number of attempts: %d", calls.Load())
+		}
+		for i, err := range errs {
+			if !errors.Is(err, errFailed) {
+				t.Errorf("Goroutine %d: expected errFailed, got %v", i, err)
+			}
+		}
+		if cell.IsInitialized() {
+			t.Error("Cell should stay empty after failed attempts")
+		}
+	})
+
+	t.Run("single attempt in flight", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var calls atomic.Int32
+
+		go func() {
+			_, _ = cell.GetOrTryInit(func() (int, error) {
+				calls.Add(1)
+				close(started)
+				<-release
+				return 1, nil
+			})
+		}()
+		<-started
+
+		const numGoroutines = 10
+		var wg sync.WaitGroup
+		wg.Add(numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			go func() {
+				defer wg.Done()
+				value, err := cell.GetOrTryInit(func() (int, error) {
+					calls.Add(1)
+					return 2, nil
+				})
+				if err != nil || value != 1 {
+					t.Errorf("Expected 1 and no error, got %d and %v", value, err)
+				}
+			}()
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		if calls.Load() != 1 {
+			t.Errorf("Expected a single attempt, got %d", calls.Load())
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		waiterErr := make(chan error, 1)
+		go func() {
+			defer func() { _ = recover() }()
+			_, _ = cell.GetOrTryInit(func() (int, error) {
+				close(started)
+				<-release
+				panic("boom")
+			})
+		}()
+		<-started
+		go func() {
+			_, err := cell.GetOrTryInit(func() (int, error) { return 3, nil })
+			waiterErr <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+
+		if err := <-waiterErr; !errors.Is(err, ErrInitPanicked) {
+			t.Errorf("Expected ErrInitPanicked, got %v", err)
+		}
+		if cell.IsInitialized() {
+			t.Error("Cell should stay empty after a panicking attempt")
+		}
+	})
+
+	t.Run("set wins", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		value, err := cell.GetOrTryInit(func() (int, error) {
+			cell.Set(5)
+			return 6, nil
+		})
+		if err != nil || value != 5 {
+			t.Errorf("Expected the value stored by Set, got %d and %v", value, err)
+		}
+	})
+
+	t.Run("nil cell", func(t *testing.T) {
+		var nilCell *OnceCell[int]
+		value, err := nilCell.GetOrTryInit(func() (int, error) { return 1, nil })
+		if err != nil || value != 0 {
+			t.Errorf("Expected zero value and no error for nil cell, got %d and %v", value, err)
+		}
+	})
+}
+
+func TestOnceCellGetOrInitContext(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		cell := NewOnceCell[string]()
+
+		value, err := cell.GetOrInitContext(context.Background(), func(context.Context) (string, error) {
+			return "ready", nil
+		})
+		if err != nil || value != "ready" {
+			t.Errorf("Expected 'ready' and no error, got '%s' and %v", value, err)
+		}
+
+		value, err = cell.GetOrInitContext(context.Background(), func(context.Context) (string, error) {
+			t.Error("Init function should not be called for initialized cell")
+			return "", nil
+		})
+		if err != nil || value != "ready" {
+			t.Errorf("Expected 'ready' and no error, got '%s' and %v", value, err)
+		}
+	})
+
+	t.Run("done context", func(t *testing.T) {
+		cell := NewOnceCell[string]()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := cell.GetOrInitContext(ctx, func(context.Context) (string, error) {
+			t.Error("Init function should not be called with a done context")
+			return "", nil
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("waiter gives up while init keeps running", func(t *testing.T) {
+		cell := NewOnceCell[string]()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var initCanceled atomic.Bool
+		init := func(ctx context.Context) (string, error) {
+			close(started)
+			select {
+			case <-release:
+				return "slow", nil
+			case <-ctx.Done():
+				initCanceled.Store(true)
+				return "", ctx.Err()
+			}
+		}
+
+		patient := make(chan string, 1)
+		go func() {
+			value, err := cell.GetOrInitContext(context.Background(), init)
+			if err != nil {
+				t.Errorf("Expected no error for patient caller, got %v", err)
+			}
+			patient <- value
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := cell.GetOrInitContext(ctx, init)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+
+		close(release)
+		if value := <-patient; value != "slow" {
+			t.Errorf("Expected 'slow', got '%s'", value)
+		}
+		if initCanceled.Load() {
+			t.Error("Init should not be cancelled while a caller is still waiting")
+		}
+	})
+
+	t.Run("init cancelled when every waiter leaves", func(t *testing.T) {
+		cell := NewOnceCell[string]()
+		initCanceled := make(chan struct{})
+		hang := func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			close(initCanceled)
+			return "", ctx.Err()
+		}
+
+		const numGoroutines = 5
+		var wg sync.WaitGroup
+		wg.Add(numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			go func() {
+				defer wg.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				defer cancel()
+				_, err := cell.GetOrInitContext(ctx, hang)
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		select {
+		case <-initCanceled:
+		case <-time.After(time.Second):
+			t.Fatal("Init should be cancelled once every caller has given up")
+		}
+
+		// A new caller starts a fresh attempt instead of joining the
+		// abandoned one.
+		value, err := cell.GetOrInitContext(context.Background(), func(context.Context) (string, error) {
+			return "fresh", nil
+		})
+		if err != nil || value != "fresh" {
+			t.Errorf("Expected 'fresh' and no error, got '%s' and %v", value, err)
+		}
+	})
+
+	t.Run("error is shared and leaves the cell empty", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		errFailed := errors.New("failed")
+		release := make(chan struct{})
+		var calls atomic.Int32
+
+		const numGoroutines = 5
+		var wg sync.WaitGroup
+		errs := make([]error, numGoroutines)
+		wg.Add(numGoroutines)
+		for i := 0; i < numGoroutines; i++ {
+			go func(id int) {
+				defer wg.Done()
+				_, errs[id] = cell.GetOrInitContext(context.Background(), func(context.Context) (int, error) {
+					calls.Add(1)
+					<-release
+					return 0, errFailed
+				})
+			}(i)
+		}
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		for i, err := range errs {
+			if !errors.Is(err, errFailed) {
+				t.Errorf("Goroutine %d: expected errFailed, got %v", i, err)
+			}
+		}
+		if calls.Load() == 0 {
+			t.Error("Expected at least one attempt")
+		}
+		if cell.IsInitialized() {
+			t.Error("Cell should stay empty after a failed attempt")
+		}
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		_, err := cell.GetOrInitContext(context.Background(), func(context.Context) (int, error) {
+			panic("boom")
+		})
+		if !errors.Is(err, ErrInitPanicked) {
+			t.Errorf("Expected ErrInitPanicked, got %v", err)
+		}
+		if cell.IsInitialized() {
+			t.Error("Cell should stay empty after a panicking attempt")
+		}
+	})
+
+	t.Run("shared with GetOrInit", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		started := make(chan struct{})
+		release := make(chan struct{})
+
+		result := make(chan int, 1)
+		go func() {
+			value, _ := cell.GetOrInitContext(context.Background(), func(context.Context) (int, error) {
+				close(started)
+				<-release
+				return 1, nil
+			})
+			result <- value
+		}()
+		<-started
+
+		done := make(chan int, 1)
+		go func() {
+			done <- cell.GetOrInit(func() int {
+				t.Error("GetOrInit should join the attempt in flight")
+				return 2
+			})
+		}()
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+
+		if value := <-done; value != 1 {
+			t.Errorf("Expected 1 from GetOrInit, got %d", value)
+		}
+		if value := <-result; value != 1 {
+			t.Errorf("Expected 1 from GetOrInitContext, got %d", value)
+		}
+	})
+
+	t.Run("set wins", func(t *testing.T) {
+		cell := NewOnceCell[int]()
+		value, err := cell.GetOrInitContext(context.Background(), func(context.Context) (int, error) {
+			if !cell.Set(5) {
+				t.Error("Set should succeed while an attempt is in flight")
+			}
+			return 6, nil
+		})
+		if err != nil || value != 5 {
+			t.Errorf("Expected the value stored by Set, got %d and %v", value, err)
+		}
+	})
+
+	t.Run("nil cell", func(t *testing.T) {
+		var nilCell *OnceCell[int]
+		value, err := nilCell.GetOrInitContext(context.Background(), func(context.Context) (int, error) {
+			return 1, nil
+		})
+		if err != nil || value != 0 {
+			t.Errorf("Expected zero value and no error for nil cell, got %d and %v", value, err)
+		}
+	})
+}
+
 func BenchmarkOnceCellResetWithCallback(b *testing.B) {
 	cell := NewOnceCell[string]()
 	cell.Set("test value")
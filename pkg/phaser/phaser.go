@@ -0,0 +1,391 @@
+// Package phaser provides a reusable synchronization barrier with a dynamic
+// number of parties, in the spirit of Java's Phaser.
+//
+// Unlike barrier.Barrier, parties may register and deregister at any time,
+// arriving is separate from waiting, and a phaser terminates once it has no
+// registered parties left (or when an OnAdvance hook says so). Phasers can be
+// tiered: a child phaser registers itself as a single party of its parent and
+// arrives at the parent once all of its own parties have arrived, so large
+// numbers of parties do not contend on a single lock.
+//
+// Example usage:
+//
+//	p := phaser.NewPhaser(1) // register the coordinator
+//	for _, task := range tasks {
+//		p.Register()
+//		go func() {
+//			defer p.ArriveAndDeregister()
+//			for step := 0; step < steps; step++ {
+//				task.Step(step)
+//				p.ArriveAndAwaitAdvance()
+//			}
+//		}()
+//	}
+//	p.ArriveAndDeregister() // let the tasks run
+package phaser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	// ErrTerminated is returned by operations on a terminated phaser.
+	ErrTerminated = errors.New("phaser: terminated")
+	// ErrNotRegistered is returned when arriving at a phaser that has no
+	// unarrived parties left in the current phase.
+	ErrNotRegistered = errors.New("phaser: no unarrived parties")
+)
+
+// Phaser is a reusable barrier whose number of parties can change over time.
+// A Phaser must not be copied after first use.
+//
+// Every phaser in a tree shares the phase of its root. Each phaser counts only
+// its own parties, guarded by its own mutex; the root additionally owns the
+// phase number and releases waiters when a phase advances.
+//
+// Once every party of a child has arrived, the child arrives at its parent
+// but keeps its counts for that phase until the root advances, like Java's
+// Phaser; registering or arriving on it meanwhile waits for the new phase.
+type Phaser struct {
+	parent *Phaser
+	root   *Phaser
+
+	mu        sync.Mutex
+	parties   int // registered parties
+	unarrived int // parties that have not arrived in phase local
+	local     int // phase the counts of a child refer to; unused on the root
+
+	// The fields below are only used on the root.
+	phase      atomic.Int64  // written under mu
+	done       chan struct{} // closed when the current phase ends
+	terminated atomic.Bool   // written under mu
+	onAdvance  func(phase, parties int) bool
+}
+
+// Option configures a Phaser created by NewPhaser.
+type Option func(*options)
+
+type options struct {
+	parent    *Phaser
+	onAdvance func(phase, parties int) bool
+}
+
+// WithParent makes the new phaser a child of parent. The child registers with
+// parent as a single party while it has registered parties of its own, and
+// arrives at parent when all of them have arrived. All phasers in a tree share
+// the root's phase and termination.
+//
+// Example:
+//
+//	root := phaser.NewPhaser(0)
+//	for i := 0; i < groups; i++ {
+//		child := phaser.NewPhaser(partiesPerGroup, phaser.WithParent(root))
+//		...
+//	}
+func WithParent(parent *Phaser) Option {
+	return func(o *options) {
+		o.parent = parent
+	}
+}
+
+// WithOnAdvance sets the hook that runs when a phase ends, before any waiter
+// is released. It receives the phase that ended and the number of parties
+// registered with the root, and returns true to terminate the phaser. The
+// default terminates the phaser once no parties are registered, like Java's
+// Phaser. The hook runs with the root locked and must not call back into the
+// phaser. It is ignored for child phasers.
+func WithOnAdvance(fn func(phase, parties int) bool) Option {
+	return func(o *options) {
+		o.onAdvance = fn
+	}
+}
+
+// NewPhaser creates a new phaser with the given number of initially
+// registered parties. It panics if parties is negative.
+func NewPhaser(parties int, opts ...Option) *Phaser {
+	if parties < 0 {
+		panic("phaser: parties must be >= 0")
+	}
+	o := options{onAdvance: func(_, parties int) bool { return parties == 0 }}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p := &Phaser{parties: parties, unarrived: parties}
+	if o.parent == nil {
+		p.root = p
+		p.done = make(chan struct{})
+		p.onAdvance = o.onAdvance
+		return p
+	}
+	p.parent = o.parent
+	p.root = o.parent.root
+	p.local = p.Phase()
+	if parties > 0 {
+		// A failed registration means the tree has terminated, which the new
+		// phaser reports through its root from now on.
+		p.local, _ = p.parent.register(1)
+	}
+	return p
+}
+
+// syncLocked brings the counts of a child up to date with the root: once the
+// root has advanced past the phase the child last arrived in, every party of
+// the child is unarrived again. The caller must hold p.mu.
+func (p *Phaser) syncLocked() {
+	if p.parent == nil {
+		return
+	}
+	if phase := p.Phase(); phase != p.local && !p.root.terminated.Load() {
+		p.local = phase
+		p.unarrived = p.parties
+	}
+}
+
+// awaitForwardedLocked waits, with p.mu released, while p is a child that has
+// already arrived at its parent in the current phase. Counting a new party or
+// arrival toward that phase would be wrong, since the parent has already
+// been told that all of p's parties arrived. It returns the phase p's counts
+// now refer to. The caller must hold p.mu, which is held again on return.
+func (p *Phaser) awaitForwardedLocked() (int, error) {
+	if p.parent == nil {
+		return p.Phase(), nil
+	}
+	for {
+		if p.root.terminated.Load() {
+			return p.Phase(), ErrTerminated
+		}
+		p.syncLocked()
+		if p.unarrived > 0 || p.parties == 0 {
+			return p.local, nil
+		}
+		phase := p.local
+		p.mu.Unlock()
+		_, err := p.root.AwaitAdvance(context.Background(), phase)
+		p.mu.Lock()
+		if err != nil {
+			return p.Phase(), err
+		}
+	}
+}
+
+// register adds n parties to p, registering p with its parent first if p had
+// no parties. Locks are taken from child to parent, never the other way.
+func (p *Phaser) register(n int) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.root.terminated.Load() {
+		return p.Phase(), ErrTerminated
+	}
+	phase, err := p.awaitForwardedLocked()
+	if err != nil {
+		return phase, err
+	}
+	if p.parent != nil && p.parties == 0 && n > 0 {
+		if phase, err = p.parent.register(1); err != nil {
+			return phase, err
+		}
+		p.local = phase
+	}
+	p.parties += n
+	p.unarrived += n
+	return phase, nil
+}
+
+// arrive records the arrival of one party in the current phase and, if it was
+// the last one, ends the phase for p. It returns the phase arrived at.
+func (p *Phaser) arrive(deregister bool) (int, error) {
+	r := p.root
+	p.mu.Lock()
+	if r.terminated.Load() {
+		phase := p.Phase()
+		p.mu.Unlock()
+		return phase, ErrTerminated
+	}
+	phase, err := p.awaitForwardedLocked()
+	if err != nil {
+		p.mu.Unlock()
+		return phase, err
+	}
+	if p.unarrived == 0 {
+		p.mu.Unlock()
+		return phase, ErrNotRegistered
+	}
+	p.unarrived--
+	if deregister {
+		p.parties--
+	}
+	if p.unarrived > 0 {
+		p.mu.Unlock()
+		return phase, nil
+	}
+	if p == r {
+		p.advanceLocked(phase)
+		p.mu.Unlock()
+		return phase, nil
+	}
+
+	// All parties of this child have arrived: arrive at the parent as a single
+	// party, leaving it if no parties remain. The counts are reset by
+	// syncLocked once the root advances.
+	leave := p.parties == 0
+	p.mu.Unlock()
+	if _, err := p.parent.arrive(leave); err != nil {
+		return phase, err
+	}
+	return phase, nil
+}
+
+// advanceLocked ends the current phase of the root and releases every waiter.
+// The caller must hold p.mu.
+func (p *Phaser) advanceLocked(phase int) {
+	close(p.done)
+	if p.onAdvance(phase, p.parties) {
+		p.terminated.Store(true)
+		return
+	}
+	p.unarrived = p.parties
+	p.phase.Store(int64(phase + 1))
+	p.done = make(chan struct{})
+}
+
+// Register adds a new party to the phaser and returns the phase it will
+// first arrive at.
+func (p *Phaser) Register() (int, error) {
+	return p.register(1)
+}
+
+// BulkRegister adds n parties to the phaser and returns the phase they will
+// first arrive at.
+func (p *Phaser) BulkRegister(n int) (int, error) {
+	if n < 0 {
+		panic("phaser: n must be >= 0")
+	}
+	return p.register(n)
+}
+
+// Arrive records the arrival of a party without waiting for the others and
+// returns the phase arrived at. Pass that phase to AwaitAdvance to wait later.
+//
+// Each registered party must arrive at most once per phase.
+func (p *Phaser) Arrive() (int, error) {
+	return p.arrive(false)
+}
+
+// ArriveAndDeregister records the arrival of a party and removes it from the
+// phaser without waiting. When the last party of the root deregisters the
+// phaser terminates, unless an OnAdvance hook decides otherwise.
+func (p *Phaser) ArriveAndDeregister() (int, error) {
+	return p.arrive(true)
+}
+
+// ArriveAndAwaitAdvance records the arrival of a party and waits for the
+// others, like barrier.Barrier.Wait. It returns the number of the next phase.
+func (p *Phaser) ArriveAndAwaitAdvance() (int, error) {
+	phase, err := p.arrive(false)
+	if err != nil {
+		return phase, err
+	}
+	return p.AwaitAdvance(context.Background(), phase)
+}
+
+// AwaitAdvance waits for the phaser to advance from the given phase and
+// returns the new phase number. If the phaser is already past phase it
+// returns immediately. Giving up because ctx is done does not affect the
+// phaser or the other parties; it only returns ctx.Err().
+//
+// Example:
+//
+//	phase, _ := p.Arrive()
+//	doIndependentWork()
+//	if _, err := p.AwaitAdvance(ctx, phase); err != nil {
+//		return err
+//	}
+func (p *Phaser) AwaitAdvance(ctx context.Context, phase int) (int, error) {
+	r := p.root
+	r.mu.Lock()
+	current := r.Phase()
+	terminated := r.terminated.Load()
+	done := r.done
+	r.mu.Unlock()
+	if terminated {
+		return current, ErrTerminated
+	}
+	if current != phase {
+		return current, nil
+	}
+	select {
+	case <-done:
+		if r.terminated.Load() {
+			return r.Phase(), ErrTerminated
+		}
+		return r.Phase(), nil
+	case <-ctx.Done():
+		return current, ctx.Err()
+	}
+}
+
+// ForceTermination terminates the whole tree of phasers, releasing every
+// waiter with ErrTerminated.
+func (p *Phaser) ForceTermination() {
+	r := p.root
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.terminated.Load() {
+		r.terminated.Store(true)
+		close(r.done)
+	}
+}
+
+// IsTerminated returns true if the phaser has terminated.
+func (p *Phaser) IsTerminated() bool {
+	return p.root.terminated.Load()
+}
+
+// Phase returns the current phase number, shared by all phasers of a tree.
+// After termination it returns the last phase.
+func (p *Phaser) Phase() int {
+	return int(p.root.phase.Load())
+}
+
+// RegisteredParties returns the number of parties registered with p.
+func (p *Phaser) RegisteredParties() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.parties
+}
+
+// UnarrivedParties returns the number of parties registered with p that have
+// not yet arrived in the current phase.
+func (p *Phaser) UnarrivedParties() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncLocked()
+	return p.unarrived
+}
+
+// ArrivedParties returns the number of parties registered with p that have
+// arrived in the current phase.
+func (p *Phaser) ArrivedParties() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncLocked()
+	return p.parties - p.unarrived
+}
+
+// Parent returns the parent of p, or nil for a root phaser.
+func (p *Phaser) Parent() *Phaser {
+	return p.parent
+}
+
+// String returns a string representation of the phaser.
+func (p *Phaser) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.syncLocked()
+	return fmt.Sprintf("Phaser{phase=%d, parties=%d, unarrived=%d, terminated=%v}",
+		p.Phase(), p.parties, p.unarrived, p.IsTerminated())
+}
@@ -0,0 +1,60 @@
+package phaser
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestPhaserConcurrentChurn stresses a tiered phaser while parties keep
+// joining and leaving between phases. It must be clean under `-race` and
+// never deadlock.
+func TestPhaserConcurrentChurn(t *testing.T) {
+	const (
+		children = 4
+		workers  = 6
+		rounds   = 30
+	)
+	root := NewPhaser(1)
+	var wg sync.WaitGroup
+	for c := 0; c < children; c++ {
+		child := NewPhaser(0, WithParent(root))
+		for w := 0; w < workers; w++ {
+			if _, err := child.Register(); err != nil {
+				t.Fatalf("register: %v", err)
+			}
+			wg.Add(1)
+			go func(rounds int) {
+				defer wg.Done()
+				for r := 0; r < rounds; r++ {
+					phase, err := child.Arrive()
+					if err != nil {
+						t.Errorf("arrive: %v", err)
+						return
+					}
+					if _, err := child.AwaitAdvance(context.Background(), phase); err != nil {
+						t.Errorf("await: %v", err)
+						return
+					}
+				}
+				if _, err := child.ArriveAndDeregister(); err != nil {
+					t.Errorf("deregister: %v", err)
+				}
+			}(rounds - w)
+		}
+	}
+
+	// The coordinator keeps the tree alive until every worker is done.
+	for root.RegisteredParties() > 1 {
+		if _, err := root.ArriveAndAwaitAdvance(); err != nil {
+			t.Fatalf("coordinator: %v", err)
+		}
+	}
+	wg.Wait()
+	if _, err := root.ArriveAndDeregister(); err != nil {
+		t.Fatalf("coordinator deregister: %v", err)
+	}
+	if !root.IsTerminated() {
+		t.Fatal("root must terminate after the last party leaves")
+	}
+}
@@ -0,0 +1,314 @@
+package phaser
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewPhaser(t *testing.T) {
+	p := NewPhaser(3)
+	require.Equal(t, 0, p.Phase())
+	require.Equal(t, 3, p.RegisteredParties())
+	require.Equal(t, 3, p.UnarrivedParties())
+	require.Equal(t, 0, p.ArrivedParties())
+	require.False(t, p.IsTerminated())
+	require.Nil(t, p.Parent())
+	require.Equal(t, "Phaser{phase=0, parties=3, unarrived=3, terminated=false}", p.String())
+
+	require.Panics(t, func() { NewPhaser(-1) })
+}
+
+func TestPhaser_ArriveAndAwaitAdvance(t *testing.T) {
+	const (
+		parties = 5
+		phases  = 4
+	)
+	p := NewPhaser(parties)
+
+	var progress [phases]atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < parties; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for phase := 0; phase < phases; phase++ {
+				progress[phase].Add(1)
+				next, err := p.ArriveAndAwaitAdvance()
+				require.NoError(t, err)
+				require.Equal(t, phase+1, next)
+				// Nobody passes before every party has finished this phase.
+				require.Equal(t, int32(parties), progress[phase].Load())
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, phases, p.Phase())
+}
+
+func TestPhaser_Arrive(t *testing.T) {
+	p := NewPhaser(2)
+
+	phase, err := p.Arrive()
+	require.NoError(t, err)
+	require.Equal(t, 0, phase)
+	require.Equal(t, 1, p.ArrivedParties())
+
+	// The phase has not advanced yet, so waiting would block.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = p.AwaitAdvance(ctx, phase)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Giving up did not disturb the phaser.
+	require.Equal(t, 1, p.ArrivedParties())
+	_, err = p.Arrive()
+	require.NoError(t, err)
+	next, err := p.AwaitAdvance(context.Background(), phase)
+	require.NoError(t, err)
+	require.Equal(t, 1, next)
+
+	// Waiting on a phase that has already passed returns immediately.
+	next, err = p.AwaitAdvance(context.Background(), 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, next)
+}
+
+func TestPhaser_DynamicRegistration(t *testing.T) {
+	p := NewPhaser(1) // the coordinator
+
+	const workers = 4
+	var steps atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		phase, err := p.Register()
+		require.NoError(t, err)
+		require.Equal(t, 0, phase)
+		wg.Add(1)
+		go func(rounds int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				steps.Add(1)
+				_, err := p.ArriveAndAwaitAdvance()
+				require.NoError(t, err)
+			}
+			_, err := p.ArriveAndDeregister()
+			require.NoError(t, err)
+		}(i + 1)
+	}
+	require.Equal(t, workers+1, p.RegisteredParties())
+
+	// The coordinator leaves, and workers drop out one by one.
+	_, err := p.ArriveAndDeregister()
+	require.NoError(t, err)
+	wg.Wait()
+
+	require.Equal(t, int32(1+2+3+4), steps.Load())
+	require.Equal(t, 0, p.RegisteredParties())
+	require.True(t, p.IsTerminated(), "phaser terminates when the last party deregisters")
+
+	_, err = p.Register()
+	require.ErrorIs(t, err, ErrTerminated)
+	_, err = p.Arrive()
+	require.ErrorIs(t, err, ErrTerminated)
+	_, err = p.AwaitAdvance(context.Background(), p.Phase())
+	require.ErrorIs(t, err, ErrTerminated)
+}
+
+func TestPhaser_BulkRegister(t *testing.T) {
+	p := NewPhaser(0)
+	phase, err := p.BulkRegister(3)
+	require.NoError(t, err)
+	require.Equal(t, 0, phase)
+	require.Equal(t, 3, p.UnarrivedParties())
+	require.Panics(t, func() { _, _ = p.BulkRegister(-1) })
+}
+
+func TestPhaser_NotRegistered(t *testing.T) {
+	p := NewPhaser(0)
+	_, err := p.Arrive()
+	require.ErrorIs(t, err, ErrNotRegistered)
+	require.False(t, p.IsTerminated())
+}
+
+func TestPhaser_OnAdvance(t *testing.T) {
+	var advanced []int
+	p := NewPhaser(1, WithOnAdvance(func(phase, parties int) bool {
+		advanced = append(advanced, phase)
+		require.Equal(t, 1, parties)
+		return phase == 2
+	}))
+
+	for i := 0; i < 2; i++ {
+		next, err := p.ArriveAndAwaitAdvance()
+		require.NoError(t, err)
+		require.Equal(t, i+1, next)
+	}
+	_, err := p.ArriveAndAwaitAdvance()
+	require.ErrorIs(t, err, ErrTerminated)
+	require.Equal(t, []int{0, 1, 2}, advanced)
+	require.True(t, p.IsTerminated())
+	require.Equal(t, 2, p.Phase())
+}
+
+func TestPhaser_ForceTermination(t *testing.T) {
+	p := NewPhaser(3)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := p.ArriveAndAwaitAdvance()
+			errs <- err
+		}()
+	}
+	require.Eventually(t, func() bool { return p.ArrivedParties() == 2 }, time.Second, time.Millisecond)
+
+	p.ForceTermination()
+	p.ForceTermination()
+	require.ErrorIs(t, <-errs, ErrTerminated)
+	require.ErrorIs(t, <-errs, ErrTerminated)
+	require.True(t, p.IsTerminated())
+}
+
+func TestPhaser_Tiered(t *testing.T) {
+	const (
+		children        = 4
+		partiesPerChild = 8
+		phases          = 5
+	)
+	root := NewPhaser(0)
+	var wg sync.WaitGroup
+	var arrivals [phases]atomic.Int32
+	for c := 0; c < children; c++ {
+		child := NewPhaser(partiesPerChild, WithParent(root))
+		require.Same(t, root, child.Parent())
+		for i := 0; i < partiesPerChild; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for phase := 0; phase < phases; phase++ {
+					arrivals[phase].Add(1)
+					next, err := child.ArriveAndAwaitAdvance()
+					require.NoError(t, err)
+					require.Equal(t, phase+1, next)
+					// The phase only advances once every party of every
+					// child has arrived.
+					require.Equal(t, int32(children*partiesPerChild), arrivals[phase].Load())
+				}
+				_, err := child.ArriveAndDeregister()
+				require.NoError(t, err)
+			}()
+		}
+	}
+	require.Equal(t, children, root.RegisteredParties())
+
+	wg.Wait()
+	// Every child deregistered from the root once its last party left.
+	require.Equal(t, 0, root.RegisteredParties())
+	require.True(t, root.IsTerminated())
+}
+
+func TestPhaser_TieredRegistration(t *testing.T) {
+	root := NewPhaser(1)
+	child := NewPhaser(0, WithParent(root))
+	require.Equal(t, 1, root.RegisteredParties(), "an empty child is not a party of its parent")
+
+	_, err := child.Register()
+	require.NoError(t, err)
+	require.Equal(t, 2, root.RegisteredParties())
+
+	// The root cannot advance until the child's party arrives.
+	_, err = root.Arrive()
+	require.NoError(t, err)
+	require.Equal(t, 0, root.Phase())
+	_, err = child.Arrive()
+	require.NoError(t, err)
+	require.Equal(t, 1, root.Phase())
+	require.Equal(t, 1, child.Phase())
+
+	// Termination of the root is shared by the child.
+	root.ForceTermination()
+	require.True(t, child.IsTerminated())
+	_, err = child.Arrive()
+	require.ErrorIs(t, err, ErrTerminated)
+}
+
+func TestPhaser_TieredRegisterAfterChildArrived(t *testing.T) {
+	root := NewPhaser(1) // direct party D
+	child := NewPhaser(1, WithParent(root))
+
+	// A, the only party of the child, arrives; the child arrives at the root,
+	// which still waits for D.
+	phase, err := child.Arrive()
+	require.NoError(t, err)
+	require.Equal(t, 0, phase)
+
+	// E registers on the child before the root advances. Its first phase must
+	// be the next one, since the child already arrived in this one.
+	registered := make(chan int, 1)
+	crossed := make(chan int, 1)
+	go func() {
+		phase, err := child.Register()
+		require.NoError(t, err)
+		registered <- phase
+		next, err := child.ArriveAndAwaitAdvance()
+		require.NoError(t, err)
+		crossed <- next
+	}()
+	select {
+	case <-registered:
+		t.Fatal("Register returned before the root advanced")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	_, err = root.Arrive() // D, phase 0
+	require.NoError(t, err)
+	require.Equal(t, 1, <-registered)
+	require.Eventually(t, func() bool { return child.ArrivedParties() == 1 }, time.Second, time.Millisecond)
+
+	// D arrives in phase 1, but A has not: E must not cross.
+	_, err = root.Arrive()
+	require.NoError(t, err)
+	select {
+	case next := <-crossed:
+		t.Fatalf("E crossed to phase %d without A", next)
+	case <-time.After(20 * time.Millisecond):
+	}
+	require.Equal(t, 1, root.Phase())
+
+	_, err = child.Arrive() // A, phase 1
+	require.NoError(t, err)
+	require.Equal(t, 2, <-crossed)
+}
+
+func TestPhaser_TieredArriveAheadOfRoot(t *testing.T) {
+	root := NewPhaser(1)
+	child := NewPhaser(1, WithParent(root))
+
+	_, err := child.Arrive()
+	require.NoError(t, err)
+
+	// Arriving again for the next phase waits until the root has advanced,
+	// instead of counting as a second arrival of the child in phase 0.
+	arrived := make(chan int, 1)
+	go func() {
+		phase, err := child.Arrive()
+		require.NoError(t, err)
+		arrived <- phase
+	}()
+	select {
+	case <-arrived:
+		t.Fatal("Arrive returned before the root advanced")
+	case <-time.After(20 * time.Millisecond):
+	}
+	require.Equal(t, 0, root.Phase())
+
+	_, err = root.Arrive()
+	require.NoError(t, err)
+	require.Equal(t, 1, <-arrived)
+	require.Equal(t, 1, root.Phase(), "the root still waits for its own party")
+}
@@ -0,0 +1,201 @@
+// Package rcu provides a read-copy-update cell for read-mostly shared data.
+//
+// Cell[T] lets readers access the current value without ever taking a lock.
+// A read pins the current version with a single atomic add, so readers never
+// retry because of each other. Writers serialize among themselves, build a new
+// copy of the value and publish it with a single atomic pointer swap. Old
+// versions are retired once no reader can still observe them; each version is
+// held in an arc.Arc[T], so snapshots obtained with Load keep a version alive
+// for as long as they are not dropped.
+//
+// Example usage:
+//
+//	routes := rcu.NewCell(map[string]string{"/": "index"})
+//	routes.Read(func(m *map[string]string) { fmt.Println((*m)["/"]) })
+//	routes.Update(func(old map[string]string) map[string]string {
+//		next := maps.Clone(old)
+//		next["/about"] = "about"
+//		return next
+//	})
+package rcu
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Gosayram/gokoncurent/pkg/arc"
+)
+
+// Cell is a read-copy-update cell holding a value of type T.
+//
+// Readers never block: Read pins the current version with a single atomic
+// add and only retries if a writer published a new version in the meantime,
+// so it completes in a bounded number of steps unless writers publish
+// continuously. Writers are serialized by a mutex and never wait for readers;
+// a retired version is released by whichever party unpins it last.
+//
+// Values passed to readers must be treated as immutable. Update receives a
+// shallow copy of the current value, so reference types such as maps and
+// slices must be copied before they are modified.
+type Cell[T any] struct {
+	mu      sync.Mutex // serializes writers
+	current atomic.Pointer[version[T]]
+}
+
+// version is one published value of a Cell.
+//
+// Pins are not counted in the Arc itself: Arc.Clone increments the count
+// unconditionally, so a reader that loaded a version just before it was
+// freed could bring the count back from zero and resurrect the value. The
+// pin count decides when the cell's own Arc reference is dropped, and that
+// Arc refcount in turn keeps the value alive for snapshots taken by Load.
+type version[T any] struct {
+	value *arc.Arc[T]
+	// state holds the retired bit and, above it, the number of readers
+	// pinning the version. Keeping both in one word lets a reader see in the
+	// result of its own add whether the version was already retired.
+	state atomic.Int64
+	freed atomic.Bool // set once the cell's reference to value has been dropped
+}
+
+const (
+	retiredBit = 1 // set once a newer version has been published
+	pinUnit    = 2 // one reader pinning the version
+)
+
+// NewCell creates a new Cell holding the given initial value.
+//
+// Example:
+//
+//	cell := NewCell(Config{Timeout: time.Second})
+func NewCell[T any](value T) *Cell[T] {
+	c := &Cell[T]{}
+	c.current.Store(&version[T]{value: arc.NewArc(value)})
+	return c
+}
+
+// pin returns the current version with its reader count incremented.
+func (c *Cell[T]) pin() *version[T] {
+	for {
+		v := c.current.Load()
+		if v.state.Add(pinUnit)&retiredBit == 0 {
+			// A writer retires a version with the same word, so either it
+			// sees our pin or we see the version retired.
+			return v
+		}
+		// Retired by a writer, which has already published a newer version.
+		// The version may already be freed; back out without using it.
+		v.unpin()
+	}
+}
+
+// unpin releases a pin taken by pin, freeing the version if it was the last
+// pin of a retired version.
+func (v *version[T]) unpin() {
+	if v.state.Add(-pinUnit) == retiredBit {
+		v.free()
+	}
+}
+
+// retire marks the version as superseded and frees it if no reader pins it.
+// It is called once per version, by the writer that replaced it.
+func (v *version[T]) retire() {
+	if v.state.Or(retiredBit) == 0 {
+		v.free()
+	}
+}
+
+// free drops the cell's reference to the value exactly once. A reader backing
+// out of a retired version can bring the count back to zero after the version
+// was freed, so free may be called more than once.
+func (v *version[T]) free() {
+	if v.freed.CompareAndSwap(false, true) {
+		v.value.Drop()
+	}
+}
+
+// Read calls fn with a pointer to the current value. It never takes a lock and
+// never waits for writers. fn must not modify the value or retain the pointer
+// after it returns; use Load for a snapshot that outlives fn.
+//
+// Example:
+//
+//	cell.Read(func(cfg *Config) {
+//		fmt.Println(cfg.Timeout)
+//	})
+func (c *Cell[T]) Read(fn func(*T)) {
+	if c == nil || fn == nil {
+		return
+	}
+	v := c.pin()
+	defer v.unpin()
+	fn(v.value.Get())
+}
+
+// Get returns a copy of the current value.
+func (c *Cell[T]) Get() T {
+	var value T
+	c.Read(func(v *T) {
+		value = *v
+	})
+	return value
+}
+
+// Load returns a snapshot of the current value as a new Arc[T] reference.
+// The snapshot stays valid after later updates and must be dropped by the
+// caller when it is no longer needed.
+//
+// Example:
+//
+//	snapshot := cell.Load()
+//	defer snapshot.Drop()
+//	fmt.Println(snapshot.Get().Timeout)
+func (c *Cell[T]) Load() *arc.Arc[T] {
+	if c == nil {
+		return nil
+	}
+	v := c.pin()
+	defer v.unpin()
+	return v.value.Clone()
+}
+
+// Update publishes a new value computed from the current one. Concurrent
+// updates are serialized, so fn always sees the latest published value.
+// Readers that started before the update keep seeing the old value until
+// they finish.
+//
+// Example:
+//
+//	cell.Update(func(old Config) Config {
+//		old.Timeout *= 2
+//		return old
+//	})
+func (c *Cell[T]) Update(fn func(old T) T) {
+	if c == nil || fn == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Writers hold c.mu and only they retire versions, so the current version
+	// cannot be freed while we read it.
+	old := c.current.Load()
+	next := fn(*old.value.Get())
+	c.current.Store(&version[T]{value: arc.NewArc(next)})
+	old.retire()
+}
+
+// Store publishes a new value, replacing the current one.
+func (c *Cell[T]) Store(value T) {
+	c.Update(func(T) T { return value })
+}
+
+// String returns a string representation of the cell.
+func (c *Cell[T]) String() string {
+	if c == nil {
+		return "<nil rcu.Cell>"
+	}
+	v := c.current.Load()
+	return fmt.Sprintf("rcu.Cell{readers=%d}", v.state.Load()/pinUnit)
+}
@@ -0,0 +1,178 @@
+package rcu
+
+import (
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Gosayram/gokoncurent/pkg/rwarcmutex"
+)
+
+func TestNewCell(t *testing.T) {
+	c := NewCell(42)
+	require.NotNil(t, c)
+	require.Equal(t, 42, c.Get())
+
+	c.Read(func(v *int) {
+		require.Equal(t, 42, *v)
+	})
+}
+
+func TestCell_UpdateAndStore(t *testing.T) {
+	c := NewCell(map[string]int{"a": 1})
+
+	c.Update(func(old map[string]int) map[string]int {
+		next := maps.Clone(old)
+		next["b"] = 2
+		return next
+	})
+	c.Read(func(m *map[string]int) {
+		require.Equal(t, map[string]int{"a": 1, "b": 2}, *m)
+	})
+
+	c.Store(map[string]int{"c": 3})
+	require.Equal(t, map[string]int{"c": 3}, c.Get())
+}
+
+func TestCell_ReaderKeepsOldVersion(t *testing.T) {
+	c := NewCell("v1")
+	old := c.current.Load()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Read(func(v *string) {
+			close(entered)
+			<-release
+			// The update below must not affect an in-flight reader.
+			require.Equal(t, "v1", *v)
+		})
+	}()
+	<-entered
+
+	// Writers never wait for readers.
+	c.Store("v2")
+	require.Equal(t, "v2", c.Get())
+	require.NotZero(t, old.state.Load()&retiredBit)
+	require.True(t, old.value.IsValid(), "version must stay alive while a reader pins it")
+
+	close(release)
+	<-done
+	require.False(t, old.value.IsValid(), "version must be freed once its last reader leaves")
+	require.Equal(t, int64(0), old.value.RefCount())
+}
+
+func TestCell_RetireWithoutReaders(t *testing.T) {
+	c := NewCell(1)
+	old := c.current.Load()
+	c.Store(2)
+	require.False(t, old.value.IsValid())
+}
+
+func TestCell_LatePinOnRetiredVersion(t *testing.T) {
+	c := NewCell(1)
+	old := c.current.Load()
+	c.Store(2)
+	require.False(t, old.value.IsValid())
+
+	// A reader that loaded old before the Store and adds its pin afterwards
+	// sees the retired bit, backs out and must not free old a second time.
+	require.NotZero(t, old.state.Add(pinUnit)&retiredBit)
+	old.unpin()
+	require.True(t, old.freed.Load())
+	require.Equal(t, int64(retiredBit), old.state.Load())
+	require.Equal(t, int64(0), old.value.RefCount())
+	require.Equal(t, 2, c.Get())
+}
+
+func TestCell_LoadSnapshot(t *testing.T) {
+	c := NewCell(1)
+	snapshot := c.Load()
+	require.Equal(t, int64(2), snapshot.RefCount())
+
+	c.Store(2)
+	// The cell released its reference; the snapshot is now the only owner.
+	require.Equal(t, 1, *snapshot.Get())
+	require.Equal(t, int64(1), snapshot.RefCount())
+	require.True(t, snapshot.Drop())
+
+	require.Equal(t, 2, c.Get())
+}
+
+func TestCell_UpdatesAreSerialized(t *testing.T) {
+	c := NewCell(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Update(func(old int) int { return old + 1 })
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 2000, c.Get())
+}
+
+func TestCell_ReadDoesNotBlockOnWriter(t *testing.T) {
+	c := NewCell(1)
+
+	inUpdate := make(chan struct{})
+	release := make(chan struct{})
+	go c.Update(func(old int) int {
+		close(inUpdate)
+		<-release
+		return old + 1
+	})
+	<-inUpdate
+
+	readDone := make(chan struct{})
+	go func() {
+		c.Read(func(v *int) { require.Equal(t, 1, *v) })
+		close(readDone)
+	}()
+	select {
+	case <-readDone:
+	case <-time.After(time.Second):
+		t.Fatal("reader blocked by an in-progress update")
+	}
+	close(release)
+}
+
+func TestCell_Nil(t *testing.T) {
+	var c *Cell[int]
+	c.Read(func(_ *int) { t.Fail() })
+	c.Update(func(_ int) int { t.Fail(); return 0 })
+	require.Nil(t, c.Load())
+	require.Equal(t, 0, c.Get())
+	require.Equal(t, "<nil rcu.Cell>", c.String())
+}
+
+func BenchmarkCellRead(b *testing.B) {
+	c := NewCell(42)
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Read(func(v *int) { _ = *v })
+		}
+	})
+}
+
+func BenchmarkRWArcMutexRead(b *testing.B) {
+	m := rwarcmutex.NewRWArcMutex(42)
+	defer m.Drop()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = m.WithRLock(func(v *int) { _ = *v })
+		}
+	})
+}
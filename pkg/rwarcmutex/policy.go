@@ -0,0 +1,169 @@
+package rwarcmutex
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Policy selects how an RWArcMutex arbitrates between readers and writers
+// competing for the lock.
+type Policy int
+
+const (
+	// WriterPreferring blocks new readers as soon as a writer is waiting, so a
+	// steady stream of readers cannot starve writers. Readers that queued behind
+	// a writer are admitted before the next writer. This is the default policy
+	// and matches sync.RWMutex.
+	WriterPreferring Policy = iota
+
+	// ReaderPreferring admits new readers whenever no writer holds the lock, even
+	// if writers are waiting. Readers never wait longer than one write critical
+	// section, but a continuous stream of overlapping readers can starve writers.
+	ReaderPreferring
+
+	// PhaseFair alternates between read phases and write phases. A reader that
+	// arrives while a writer holds or waits for the lock waits for at most one
+	// write phase, and a writer waits for at most one read phase plus the writers
+	// queued ahead of it.
+	PhaseFair
+)
+
+// String returns the name of the policy.
+func (p Policy) String() string {
+	switch p {
+	case WriterPreferring:
+		return "WriterPreferring"
+	case ReaderPreferring:
+		return "ReaderPreferring"
+	case PhaseFair:
+		return "PhaseFair"
+	default:
+		return fmt.Sprintf("Policy(%d)", int(p))
+	}
+}
+
+// Option configures an RWArcMutex created by NewRWArcMutex.
+type Option func(*options)
+
+type options struct {
+	policy   Policy
+	readBias bool
+}
+
+// WithPolicy selects the reader/writer preference policy of the lock.
+//
+// Example:
+//
+//	m := NewRWArcMutex(stats, WithPolicy(ReaderPreferring))
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policy = p
+	}
+}
+
+// rwLocker is the lock implementation behind an RWArcMutex.
+type rwLocker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+// newRWLocker returns the lock implementation for the given policy.
+func newRWLocker(p Policy) rwLocker {
+	switch p {
+	case ReaderPreferring, PhaseFair:
+		l := &policyLock{policy: p}
+		l.cond = sync.NewCond(&l.mu)
+		return l
+	default:
+		return &sync.RWMutex{}
+	}
+}
+
+// policyLock is a read-write lock implementing the ReaderPreferring and
+// PhaseFair policies on top of a mutex and a condition variable.
+type policyLock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	policy Policy
+
+	readers        int    // readers holding (or admitted to) the lock
+	writer         bool   // whether a writer holds the lock
+	waitingWriters int    // writers blocked in Lock
+	waitingReaders int    // readers blocked in RLock
+	writePhase     uint64 // number of completed write phases
+}
+
+// RLock acquires the lock for reading.
+func (l *policyLock) RLock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.policy == ReaderPreferring {
+		l.waitingReaders++
+		for l.writer {
+			l.cond.Wait()
+		}
+		l.waitingReaders--
+		l.readers++
+		return
+	}
+
+	if !l.writer && l.waitingWriters == 0 {
+		l.readers++
+		return
+	}
+	// Wait for the current or next write phase to end. The releasing writer
+	// admits us by counting us as an active reader.
+	phase := l.writePhase
+	l.waitingReaders++
+	for l.writePhase == phase {
+		l.cond.Wait()
+	}
+}
+
+// RUnlock releases a read lock.
+func (l *policyLock) RUnlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.readers <= 0 {
+		panic("rwarcmutex: RUnlock of unlocked lock")
+	}
+	l.readers--
+	if l.readers == 0 {
+		l.cond.Broadcast()
+	}
+}
+
+// Lock acquires the lock for writing.
+func (l *policyLock) Lock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.waitingWriters++
+	// Under ReaderPreferring a writer also yields to readers that are already
+	// waiting, so a writer re-acquiring in a loop cannot barge past them.
+	for l.writer || l.readers > 0 || (l.policy == ReaderPreferring && l.waitingReaders > 0) {
+		l.cond.Wait()
+	}
+	l.waitingWriters--
+	l.writer = true
+}
+
+// Unlock releases the write lock.
+func (l *policyLock) Unlock() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.writer {
+		panic("rwarcmutex: Unlock of unlocked lock")
+	}
+	l.writer = false
+	l.writePhase++
+	if l.policy == PhaseFair {
+		// Hand the lock to every reader that queued during this write phase
+		// before any waiting writer gets another turn.
+		l.readers += l.waitingReaders
+		l.waitingReaders = 0
+	}
+	l.cond.Broadcast()
+}
@@ -0,0 +1,289 @@
+package rwarcmutex
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var allPolicies = []Policy{WriterPreferring, ReaderPreferring, PhaseFair}
+
+// orderRecorder records the order in which lock holders entered their critical sections.
+type orderRecorder struct {
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *orderRecorder) add(name string) {
+	r.mu.Lock()
+	r.order = append(r.order, name)
+	r.mu.Unlock()
+}
+
+func (r *orderRecorder) get() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.order...)
+}
+
+// holdRead acquires a read lock in a new goroutine and keeps it until release is closed.
+func holdRead(m *RWArcMutex[int], release <-chan struct{}) {
+	entered := make(chan struct{})
+	go m.WithRLock(func(_ *int) {
+		close(entered)
+		<-release
+	})
+	<-entered
+}
+
+func TestPolicy_String(t *testing.T) {
+	require.Equal(t, "WriterPreferring", WriterPreferring.String())
+	require.Equal(t, "ReaderPreferring", ReaderPreferring.String())
+	require.Equal(t, "PhaseFair", PhaseFair.String())
+	require.Equal(t, "Policy(42)", Policy(42).String())
+}
+
+func TestPolicy_ConcurrentAccess(t *testing.T) {
+	for _, p := range allPolicies {
+		t.Run(p.String(), func(t *testing.T) {
+			m := NewRWArcMutex(0, WithPolicy(p))
+			defer m.Drop()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						m.WithLock(func(v *int) { *v++ })
+					}
+				}()
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 200; j++ {
+						m.WithRLock(func(v *int) { _ = *v })
+					}
+				}()
+			}
+			wg.Wait()
+			m.WithRLock(func(v *int) { require.Equal(t, 1600, *v) })
+		})
+	}
+}
+
+func TestPolicy_Upgradable(t *testing.T) {
+	for _, p := range allPolicies {
+		t.Run(p.String(), func(t *testing.T) {
+			m := NewRWArcMutex(0, WithPolicy(p))
+			defer m.Drop()
+
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					m.WithUpgradableRLock(func(v *int, upgrade func(func(*int))) {
+						if *v%2 == 0 {
+							upgrade(func(v *int) { *v++ })
+						}
+					})
+				}()
+				go func() {
+					defer wg.Done()
+					m.WithRLock(func(v *int) { _ = *v })
+				}()
+			}
+			wg.Wait()
+			m.WithRLock(func(v *int) { require.Equal(t, 1, *v) })
+		})
+	}
+}
+
+func TestPolicy_WriterPreferring_QueuedWriterBlocksNewReaders(t *testing.T) {
+	m := NewRWArcMutex(0, WithPolicy(WriterPreferring))
+	defer m.Drop()
+
+	var rec orderRecorder
+	release := make(chan struct{})
+	holdRead(m, release)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		m.WithLock(func(_ *int) { rec.add("writer") })
+	}()
+	time.Sleep(20 * time.Millisecond)
+	go func() {
+		defer wg.Done()
+		m.WithRLock(func(_ *int) { rec.add("reader") })
+	}()
+	time.Sleep(20 * time.Millisecond)
+	require.Empty(t, rec.get(), "late reader must queue behind the waiting writer")
+
+	close(release)
+	wg.Wait()
+	require.Equal(t, []string{"writer", "reader"}, rec.get())
+}
+
+func TestPolicy_ReaderPreferring_ReadersPassQueuedWriter(t *testing.T) {
+	m := NewRWArcMutex(0, WithPolicy(ReaderPreferring))
+	defer m.Drop()
+
+	var rec orderRecorder
+	release := make(chan struct{})
+	holdRead(m, release)
+
+	writerDone := make(chan struct{})
+	go func() {
+		m.WithLock(func(_ *int) { rec.add("writer") })
+		close(writerDone)
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	readerDone := make(chan struct{})
+	go func() {
+		m.WithRLock(func(_ *int) { rec.add("reader") })
+		close(readerDone)
+	}()
+	select {
+	case <-readerDone:
+	case <-time.After(time.Second):
+		t.Fatal("reader stalled behind a queued writer")
+	}
+
+	close(release)
+	<-writerDone
+	require.Equal(t, []string{"reader", "writer"}, rec.get())
+}
+
+func TestPolicy_PhaseFair_ReaderWaitsOneWritePhase(t *testing.T) {
+	m := NewRWArcMutex(0, WithPolicy(PhaseFair))
+	defer m.Drop()
+
+	var rec orderRecorder
+	release := make(chan struct{})
+	holdRead(m, release)
+
+	var wg sync.WaitGroup
+	start := func(name string, write bool) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if write {
+				m.WithLock(func(_ *int) {
+					rec.add(name)
+					time.Sleep(5 * time.Millisecond)
+				})
+				return
+			}
+			m.WithRLock(func(_ *int) {
+				rec.add(name)
+				time.Sleep(5 * time.Millisecond)
+			})
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	start("writer1", true)
+	start("reader", false)
+	start("writer2", true)
+	require.Empty(t, rec.get())
+
+	close(release)
+	wg.Wait()
+	// The reader that arrived behind writer1 runs right after writer1's phase,
+	// before writer2, even though writer2 was already waiting.
+	require.Equal(t, []string{"writer1", "reader", "writer2"}, rec.get())
+}
+
+// TestPolicy_BoundedReaderWait checks that under every policy a reader makes
+// progress while writers hammer the lock continuously.
+func TestPolicy_BoundedReaderWait(t *testing.T) {
+	for _, p := range allPolicies {
+		t.Run(p.String(), func(t *testing.T) {
+			m := NewRWArcMutex(0, WithPolicy(p))
+			defer m.Drop()
+
+			var stop atomic.Bool
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for !stop.Load() {
+						m.WithLock(func(v *int) {
+							*v++
+							time.Sleep(time.Millisecond)
+						})
+					}
+				}()
+			}
+			defer func() {
+				stop.Store(true)
+				wg.Wait()
+			}()
+
+			time.Sleep(10 * time.Millisecond)
+			for i := 0; i < 5; i++ {
+				done := make(chan struct{})
+				go func() {
+					m.WithRLock(func(_ *int) {})
+					close(done)
+				}()
+				select {
+				case <-done:
+				case <-time.After(2 * time.Second):
+					t.Fatalf("reader starved under %v", p)
+				}
+			}
+		})
+	}
+}
+
+// TestPolicy_BoundedWriterWait checks that writer-preferring and phase-fair
+// locks let a writer in while overlapping readers keep the lock read-held at
+// all times. ReaderPreferring deliberately gives no such guarantee.
+func TestPolicy_BoundedWriterWait(t *testing.T) {
+	for _, p := range []Policy{WriterPreferring, PhaseFair} {
+		t.Run(p.String(), func(t *testing.T) {
+			m := NewRWArcMutex(0, WithPolicy(p))
+			defer m.Drop()
+
+			var stop atomic.Bool
+			var wg sync.WaitGroup
+			for i := 0; i < 8; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for !stop.Load() {
+						m.WithRLock(func(_ *int) {
+							time.Sleep(2 * time.Millisecond)
+						})
+					}
+				}()
+			}
+			defer func() {
+				stop.Store(true)
+				wg.Wait()
+			}()
+
+			time.Sleep(10 * time.Millisecond)
+			for i := 0; i < 5; i++ {
+				done := make(chan struct{})
+				go func() {
+					m.WithLock(func(v *int) { *v++ })
+					close(done)
+				}()
+				select {
+				case <-done:
+				case <-time.After(2 * time.Second):
+					t.Fatalf("writer starved under %v", p)
+				}
+			}
+		})
+	}
+}
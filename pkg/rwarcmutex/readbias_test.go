@@ -0,0 +1,168 @@
+package rwarcmutex
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pair struct {
+	a, b int
+}
+
+func TestReadBias_ConsistentReads(t *testing.T) {
+	for _, p := range allPolicies {
+		t.Run(p.String(), func(t *testing.T) {
+			m := NewRWArcMutex(pair{}, WithPolicy(p), WithReadBias())
+			defer m.Drop()
+
+			var torn atomic.Int64
+			var wg sync.WaitGroup
+			for i := 0; i < 4; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 500; j++ {
+						m.WithLock(func(v *pair) {
+							v.a++
+							v.b++
+						})
+					}
+				}()
+			}
+			for i := 0; i < 16; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < 2000; j++ {
+						m.WithRLock(func(v *pair) {
+							if v.a != v.b {
+								torn.Add(1)
+							}
+						})
+					}
+				}()
+			}
+			wg.Wait()
+
+			require.Zero(t, torn.Load(), "reader observed a partial write")
+			m.WithRLock(func(v *pair) { require.Equal(t, pair{2000, 2000}, *v) })
+		})
+	}
+}
+
+func TestReadBias_WriterWaitsForBiasedReader(t *testing.T) {
+	m := NewRWArcMutex(0, WithReadBias())
+	defer m.Drop()
+	require.True(t, m.shared.bias.enabled.Load())
+
+	release := make(chan struct{})
+	holdRead(m, release)
+
+	written := make(chan struct{})
+	go func() {
+		m.WithLock(func(v *int) { *v = 1 })
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("writer entered while a biased reader held the lock")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-written
+	m.WithRLock(func(v *int) { require.Equal(t, 1, *v) })
+}
+
+func TestReadBias_RevokeAndReenable(t *testing.T) {
+	m := NewRWArcMutex(0, WithReadBias())
+	defer m.Drop()
+
+	m.WithLock(func(v *int) { *v++ })
+	require.False(t, m.shared.bias.enabled.Load(), "writer must revoke read bias")
+
+	// Force the inhibition window to expire; the next slow-path reader
+	// re-enables the bias.
+	m.shared.bias.inhibitUntil.Store(0)
+	m.WithRLock(func(_ *int) {})
+	require.True(t, m.shared.bias.enabled.Load())
+
+	// Biased readers leave no trace in the slots once they are done.
+	m.WithRLock(func(_ *int) {})
+	for i := range m.shared.bias.slots {
+		require.Zero(t, m.shared.bias.slots[i].readers.Load())
+	}
+}
+
+func TestReadBias_UpgradeAndDowngrade(t *testing.T) {
+	m := NewRWArcMutex(0, WithReadBias())
+	defer m.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			m.WithUpgradableRLock(func(_ *int, upgrade func(func(*int))) {
+				upgrade(func(v *int) { *v++ })
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			m.WithLockDowngrade(func(v *int) { *v++ }, func(_ *int) {})
+		}()
+		go func() {
+			defer wg.Done()
+			m.WithRLock(func(_ *int) {})
+		}()
+	}
+	wg.Wait()
+	m.WithRLock(func(v *int) { require.Equal(t, 40, *v) })
+}
+
+// benchmarkReads measures WithRLock throughput with the given options,
+// performing one write every writeEvery reads (0 disables writes).
+func benchmarkReads(b *testing.B, writeEvery int, opts ...Option) {
+	m := NewRWArcMutex(pair{}, opts...)
+	defer m.Drop()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		n := 0
+		for pb.Next() {
+			n++
+			if writeEvery > 0 && n%writeEvery == 0 {
+				m.WithLock(func(v *pair) { v.a++ })
+				continue
+			}
+			m.WithRLock(func(v *pair) { _ = v.a })
+		}
+	})
+}
+
+func BenchmarkRWArcMutexWithRLock(b *testing.B) {
+	b.Run("Default", func(b *testing.B) { benchmarkReads(b, 0) })
+	b.Run("ReadBias", func(b *testing.B) { benchmarkReads(b, 0, WithReadBias()) })
+}
+
+func BenchmarkRWArcMutexWithRLockManyGoroutines(b *testing.B) {
+	b.Run("Default", func(b *testing.B) {
+		b.SetParallelism(8)
+		benchmarkReads(b, 0)
+	})
+	b.Run("ReadBias", func(b *testing.B) {
+		b.SetParallelism(8)
+		benchmarkReads(b, 0, WithReadBias())
+	})
+}
+
+func BenchmarkRWArcMutexReadMostly(b *testing.B) {
+	b.Run("Default", func(b *testing.B) { benchmarkReads(b, 1000) })
+	b.Run("ReadBias", func(b *testing.B) { benchmarkReads(b, 1000, WithReadBias()) })
+}
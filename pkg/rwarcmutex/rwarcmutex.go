@@ -1,93 +1,324 @@
 // Package rwarcmutex provides a thread-safe reference-counted read-write mutex for shared mutable state.
 //
 // RWArcMutex[T] allows multiple readers or one writer, with atomic reference counting
-// for safe sharing between goroutines.
+// for safe sharing between goroutines. In addition to plain read and write access it
+// supports upgradable reads: at most one upgradable reader may coexist with plain
+// readers and atomically upgrade to write access, following parking_lot's RwLock.
+//
+// The reader/writer preference policy is chosen at construction time with
+// WithPolicy; the default is WriterPreferring, the behavior of sync.RWMutex.
+// Read-mostly data on many cores can opt into a scalable read path with
+// WithReadBias.
 //
 // Example usage:
 //
 //	m := rwarcmutex.NewRWArcMutex(42)
 //	m.WithRLock(func(v *int) { fmt.Println(*v) })
 //	m.WithLock(func(v *int) { *v = 100 })
+//	m.WithUpgradableRLock(func(v *int, upgrade func(func(*int))) {
+//		if *v < 200 {
+//			upgrade(func(v *int) { *v = 200 })
+//		}
+//	})
 //	clone := m.Clone()
 //	m.Drop()
 //	clone.Drop()
+//
+// Like arc.Arc, every Clone returns a distinct handle with its own dropped flag.
+// Dropping a handle twice is a no-op, and a dropped handle can no longer be
+// used, so RefCount always matches the number of live handles.
+//
+// Drop coordinates with goroutines already inside a lock method: the last Drop
+// releases the value under the write lock, after every in-flight holder has
+// left, and lock methods called through a dropped handle, or that acquire the
+// lock after the last Drop, return ErrDropped without running their callback.
 package rwarcmutex
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 	"sync/atomic"
 )
 
+// ErrDropped is returned by the lock methods of a dropped RWArcMutex handle or
+// of a mutex whose last reference has been dropped.
+var ErrDropped = errors.New("rwarcmutex: mutex has been dropped")
+
 // RWArcMutex provides a reference-counted, thread-safe read-write mutex for shared mutable state of type T.
+//
+// An RWArcMutex is a handle to shared state. Each handle must be dropped exactly once.
 type RWArcMutex[T any] struct {
-	mu     sync.RWMutex
-	refcnt atomic.Int64
-	value  *T
-	closed atomic.Bool
+	shared  *rwShared[T]
+	dropped atomic.Bool
+}
+
+// rwShared is the state shared by all handles of an RWArcMutex.
+type rwShared[T any] struct {
+	mu rwLocker
+	// upgrade is held by writers and by the single upgradable reader. Holding it
+	// guarantees that no other writer can slip in while an upgradable reader
+	// trades its read lock for the write lock, or while a writer downgrades.
+	upgrade sync.Mutex
+	bias    *readBias // nil unless WithReadBias was given
+	refcnt  atomic.Int64
+	value   *T // guarded by the write lock once closed is set
+	closed  atomic.Bool
 }
 
 // NewRWArcMutex creates a new RWArcMutex with the given initial value.
-func NewRWArcMutex[T any](value T) *RWArcMutex[T] {
-	m := &RWArcMutex[T]{
+// Options such as WithPolicy configure the underlying lock.
+func NewRWArcMutex[T any](value T, opts ...Option) *RWArcMutex[T] {
+	o := options{policy: WriterPreferring}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	s := &rwShared[T]{
+		mu:    newRWLocker(o.policy),
 		value: &value,
 	}
-	m.refcnt.Store(1)
-	return m
+	if o.readBias {
+		s.bias = newReadBias()
+	}
+	s.refcnt.Store(1)
+	return &RWArcMutex[T]{shared: s}
+}
+
+// rlock acquires a read lock and returns the reader slot to pass to runlock.
+func (s *rwShared[T]) rlock() int {
+	if slot := s.bias.tryRLock(); slot >= 0 {
+		return slot
+	}
+	s.mu.RLock()
+	s.bias.maybeEnable()
+	return -1
+}
+
+// runlock releases a read lock acquired by rlock.
+func (s *rwShared[T]) runlock(slot int) {
+	if slot >= 0 {
+		s.bias.runlock(slot)
+		return
+	}
+	s.mu.RUnlock()
+}
+
+// lock acquires the write lock, revoking read bias if necessary.
+func (s *rwShared[T]) lock() {
+	s.mu.Lock()
+	s.bias.revoke()
+}
+
+// release closes the mutex and frees the value. Taking the write lock waits
+// for every goroutine already inside a lock method; goroutines queued behind
+// us observe closed once they acquire the lock and never touch the value.
+func (s *rwShared[T]) release() {
+	s.closed.Store(true)
+	s.upgrade.Lock()
+	defer s.upgrade.Unlock()
+	s.lock()
+	defer s.mu.Unlock()
+	s.value = nil
 }
 
-// Clone creates a new reference to the same underlying value.
+// handle returns the shared state if m is a live handle of an open mutex,
+// or nil otherwise.
+func (m *RWArcMutex[T]) handle() *rwShared[T] {
+	if m == nil || m.shared == nil || m.dropped.Load() || m.shared.closed.Load() {
+		return nil
+	}
+	return m.shared
+}
+
+// Clone creates a new handle to the same underlying value.
+// It returns nil if m has been dropped or the mutex has been closed.
 func (m *RWArcMutex[T]) Clone() *RWArcMutex[T] {
-	if m == nil || m.closed.Load() {
+	s := m.handle()
+	if s == nil {
 		return nil
 	}
-	m.refcnt.Add(1)
-	return m
+	for {
+		current := s.refcnt.Load()
+		if current <= 0 {
+			return nil
+		}
+		if s.refcnt.CompareAndSwap(current, current+1) {
+			return &RWArcMutex[T]{shared: s}
+		}
+	}
 }
 
-// Drop decrements the reference count and cleans up if it reaches zero.
-func (m *RWArcMutex[T]) Drop() {
-	if m == nil {
-		return
+// Drop releases this handle and decrements the reference count. Dropping the
+// same handle more than once has no effect.
+//
+// When the last handle is dropped the mutex is closed: new lock calls fail with
+// ErrDropped, and Drop waits for every goroutine currently inside a lock method
+// to leave before releasing the value. The last Drop must therefore not be
+// called from inside a lock callback of the same mutex.
+//
+// Returns true if this was the last reference.
+func (m *RWArcMutex[T]) Drop() bool {
+	if m == nil || m.shared == nil || !m.dropped.CompareAndSwap(false, true) {
+		return false
 	}
-	if m.refcnt.Add(-1) == 0 {
-		m.closed.Store(true)
-		m.value = nil
+	s := m.shared
+	if s.refcnt.Add(-1) != 0 {
+		return false
 	}
+	s.release()
+	return true
 }
 
-// RefCount returns the current reference count.
+// RefCount returns the number of live handles to the underlying value.
 func (m *RWArcMutex[T]) RefCount() int64 {
-	if m == nil {
+	if m == nil || m.shared == nil {
 		return 0
 	}
-	return m.refcnt.Load()
+	return m.shared.refcnt.Load()
+}
+
+// IsValid returns true if this handle has not been dropped and the mutex has
+// not been closed.
+func (m *RWArcMutex[T]) IsValid() bool {
+	return m.handle() != nil
 }
 
 // WithRLock executes fn with a read lock on the value.
-func (m *RWArcMutex[T]) WithRLock(fn func(*T)) {
-	if m == nil || m.closed.Load() {
-		return
+// It returns ErrDropped, without calling fn, if the mutex has been dropped.
+func (m *RWArcMutex[T]) WithRLock(fn func(*T)) error {
+	s := m.handle()
+	if s == nil {
+		return ErrDropped
+	}
+	slot := s.rlock()
+	defer s.runlock(slot)
+	if s.closed.Load() {
+		return ErrDropped
 	}
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	fn(m.value)
+	fn(s.value)
+	return nil
 }
 
 // WithLock executes fn with a write lock on the value.
-func (m *RWArcMutex[T]) WithLock(fn func(*T)) {
-	if m == nil || m.closed.Load() {
-		return
+// It returns ErrDropped, without calling fn, if the mutex has been dropped.
+func (m *RWArcMutex[T]) WithLock(fn func(*T)) error {
+	s := m.handle()
+	if s == nil {
+		return ErrDropped
+	}
+	s.upgrade.Lock()
+	defer s.upgrade.Unlock()
+	s.lock()
+	defer s.mu.Unlock()
+	if s.closed.Load() {
+		return ErrDropped
+	}
+	fn(s.value)
+	return nil
+}
+
+// WithUpgradableRLock executes fn with an upgradable read lock on the value.
+//
+// An upgradable read lock is shared with plain readers (WithRLock) but excludes
+// writers and other upgradable readers. Calling upgrade from inside fn atomically
+// upgrades to a write lock, waiting for plain readers to leave, runs writeFn with
+// exclusive access and then downgrades back to an upgradable read lock before
+// returning. No other writer can modify the value between the read in fn and the
+// write in writeFn, which makes lookup-then-insert patterns race-free.
+//
+// upgrade must only be called from the goroutine running fn and only before fn
+// returns. Calling WithLock or WithUpgradableRLock from inside fn deadlocks.
+// It returns ErrDropped, without calling fn, if the mutex has been dropped.
+//
+// Example:
+//
+//	cache := NewRWArcMutex(map[string]int{})
+//	cache.WithUpgradableRLock(func(m *map[string]int, upgrade func(func(*map[string]int))) {
+//		if _, ok := (*m)["key"]; !ok {
+//			upgrade(func(m *map[string]int) { (*m)["key"] = 42 })
+//		}
+//	})
+func (m *RWArcMutex[T]) WithUpgradableRLock(fn func(v *T, upgrade func(writeFn func(*T)))) error {
+	s := m.handle()
+	if s == nil {
+		return ErrDropped
 	}
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	fn(m.value)
+	s.upgrade.Lock()
+	defer s.upgrade.Unlock()
+	s.mu.RLock()
+	// The read lock is held again after every upgrade, so a single deferred
+	// release covers both the plain and the upgraded path.
+	defer s.mu.RUnlock()
+	if s.closed.Load() {
+		return ErrDropped
+	}
+
+	upgrade := func(writeFn func(*T)) {
+		// Writers must hold s.upgrade, which we own, so releasing the read lock
+		// here cannot let another writer in before we take the write lock.
+		s.mu.RUnlock()
+		s.lock()
+		defer func() {
+			s.mu.Unlock()
+			s.mu.RLock()
+		}()
+		writeFn(s.value)
+	}
+	fn(s.value, upgrade)
+	return nil
+}
+
+// WithLockDowngrade executes writeFn with a write lock on the value and then
+// atomically downgrades to a read lock to execute readFn.
+//
+// Plain readers may enter as soon as writeFn returns, but no other writer can
+// modify the value before readFn has observed the result of writeFn.
+// It returns ErrDropped, without calling either function, if the mutex has
+// been dropped.
+//
+// Example:
+//
+//	m := NewRWArcMutex(0)
+//	m.WithLockDowngrade(
+//		func(v *int) { *v = 42 },
+//		func(v *int) { fmt.Println(*v) }, // always prints 42
+//	)
+func (m *RWArcMutex[T]) WithLockDowngrade(writeFn, readFn func(*T)) error {
+	s := m.handle()
+	if s == nil {
+		return ErrDropped
+	}
+	s.upgrade.Lock()
+	s.lock()
+	downgraded := false
+	defer func() {
+		if downgraded {
+			s.mu.RUnlock()
+		} else {
+			s.mu.Unlock()
+			s.upgrade.Unlock()
+		}
+	}()
+	if s.closed.Load() {
+		return ErrDropped
+	}
+	writeFn(s.value)
+
+	// Holding s.upgrade keeps other writers out while we swap the write lock
+	// for a read lock. Once the read lock is held, writers may queue on
+	// s.upgrade again; they will block on s.mu until readFn returns.
+	s.mu.Unlock()
+	s.mu.RLock()
+	s.upgrade.Unlock()
+	downgraded = true
+	readFn(s.value)
+	return nil
 }
 
 // String returns a string representation of the RWArcMutex.
 func (m *RWArcMutex[T]) String() string {
-	if m == nil {
+	if m == nil || m.shared == nil {
 		return "<nil RWArcMutex>"
 	}
-	return fmt.Sprintf("RWArcMutex{refcnt=%d, closed=%v}", m.RefCount(), m.closed.Load())
+	return fmt.Sprintf("RWArcMutex{refcnt=%d, closed=%v}", m.RefCount(), m.shared.closed.Load())
 }
@@ -1,7 +1,9 @@
 package rwarcmutex
 
 import (
+	"errors"
 	"sync"
+	"sync/atomic"
 	"testing"
 )
 
@@ -59,3 +61,59 @@ func TestRWArcMutexConcurrentAccess(t *testing.T) {
 		t.Fatalf("unexpected final value: want %d, got %d", expected, final)
 	}
 }
+
+// TestRWArcMutexDropDuringAccess stresses Drop racing with readers, writers and
+// upgradable readers that are already inside the mutex. Run with `-race` to
+// verify that teardown never races with in-flight holders.
+func TestRWArcMutexDropDuringAccess(t *testing.T) {
+	const (
+		rounds     = 200
+		goroutines = 8
+	)
+
+	for r := 0; r < rounds; r++ {
+		m := NewRWArcMutex(0)
+		handles := make([]*RWArcMutex[int], goroutines)
+		for i := range handles {
+			handles[i] = m.Clone()
+		}
+
+		var wg sync.WaitGroup
+		var unexpected atomic.Int64
+		check := func(err error) {
+			if err != nil && !errors.Is(err, ErrDropped) {
+				unexpected.Add(1)
+			}
+		}
+		wg.Add(goroutines)
+		for i := 0; i < goroutines; i++ {
+			go func(h *RWArcMutex[int], id int) {
+				defer wg.Done()
+				defer h.Drop()
+				for j := 0; j < 20; j++ {
+					switch (id + j) % 3 {
+					case 0:
+						check(h.WithRLock(func(v *int) { _ = *v }))
+					case 1:
+						check(h.WithLock(func(v *int) { *v++ }))
+					default:
+						check(h.WithUpgradableRLock(func(v *int, upgrade func(func(*int))) {
+							if *v%2 == 0 {
+								upgrade(func(v *int) { *v++ })
+							}
+						}))
+					}
+				}
+			}(handles[i], i)
+		}
+		m.Drop()
+		wg.Wait()
+
+		if unexpected.Load() != 0 {
+			t.Fatalf("unexpected errors from lock methods: %d", unexpected.Load())
+		}
+		if m.RefCount() != 0 || m.shared.value != nil {
+			t.Fatalf("mutex not torn down: refcnt=%d value=%v", m.RefCount(), m.shared.value)
+		}
+	}
+}
@@ -95,3 +95,292 @@ func TestRWArcMutex_NilAndClosed(t *testing.T) {
 	m2.WithRLock(func(_ *int) { t.Fail() })
 	m2.WithLock(func(_ *int) { t.Fail() })
 }
+
+func TestRWArcMutex_UpgradableRLock(t *testing.T) {
+	m := NewRWArcMutex(map[string]int{})
+	defer m.Drop()
+
+	var inserts int
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.WithUpgradableRLock(func(v *map[string]int, upgrade func(func(*map[string]int))) {
+				if _, ok := (*v)["key"]; ok {
+					return
+				}
+				upgrade(func(v *map[string]int) {
+					inserts++
+					(*v)["key"] = 42
+				})
+				// Still holding the (downgraded) upgradable read lock.
+				require.Equal(t, 42, (*v)["key"])
+			})
+		}()
+	}
+	wg.Wait()
+
+	require.Equal(t, 1, inserts)
+}
+
+func TestRWArcMutex_UpgradableRLock_SharedWithReaders(t *testing.T) {
+	m := NewRWArcMutex(1)
+	defer m.Drop()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		m.WithUpgradableRLock(func(_ *int, _ func(func(*int))) {
+			close(entered)
+			<-release
+		})
+	}()
+	<-entered
+
+	// A plain reader must not be blocked by the upgradable reader.
+	readDone := make(chan struct{})
+	go func() {
+		m.WithRLock(func(v *int) { require.Equal(t, 1, *v) })
+		close(readDone)
+	}()
+	select {
+	case <-readDone:
+	case <-time.After(time.Second):
+		t.Fatal("plain reader blocked by upgradable reader")
+	}
+
+	// A second upgradable reader and a writer must wait.
+	var blocked sync.WaitGroup
+	blocked.Add(2)
+	var passed int32
+	var mu sync.Mutex
+	go func() {
+		defer blocked.Done()
+		m.WithUpgradableRLock(func(_ *int, _ func(func(*int))) {
+			mu.Lock()
+			passed++
+			mu.Unlock()
+		})
+	}()
+	go func() {
+		defer blocked.Done()
+		m.WithLock(func(_ *int) {
+			mu.Lock()
+			passed++
+			mu.Unlock()
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	require.Equal(t, int32(0), passed)
+	mu.Unlock()
+
+	close(release)
+	blocked.Wait()
+	require.Equal(t, int32(2), passed)
+}
+
+func TestRWArcMutex_UpgradeWaitsForReaders(t *testing.T) {
+	m := NewRWArcMutex(0)
+	defer m.Drop()
+
+	readerIn := make(chan struct{})
+	readerOut := make(chan struct{})
+	go func() {
+		m.WithRLock(func(_ *int) {
+			close(readerIn)
+			<-readerOut
+		})
+	}()
+	<-readerIn
+
+	upgraded := make(chan struct{})
+	go func() {
+		m.WithUpgradableRLock(func(_ *int, upgrade func(func(*int))) {
+			upgrade(func(v *int) { *v = 7 })
+			close(upgraded)
+		})
+	}()
+
+	select {
+	case <-upgraded:
+		t.Fatal("upgrade must wait for active readers")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(readerOut)
+	<-upgraded
+	m.WithRLock(func(v *int) { require.Equal(t, 7, *v) })
+}
+
+func TestRWArcMutex_WithLockDowngrade(t *testing.T) {
+	m := NewRWArcMutex(0)
+	defer m.Drop()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			m.WithLockDowngrade(
+				func(v *int) { *v = id },
+				func(v *int) {
+					// No writer may run between the write and this read.
+					time.Sleep(time.Millisecond)
+					require.Equal(t, id, *v)
+				},
+			)
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.WithLock(func(v *int) { *v = -1 })
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRWArcMutex_WithLockDowngrade_AdmitsReaders(t *testing.T) {
+	m := NewRWArcMutex(0)
+	defer m.Drop()
+
+	readDone := make(chan struct{})
+	m.WithLockDowngrade(
+		func(v *int) { *v = 5 },
+		func(_ *int) {
+			go func() {
+				m.WithRLock(func(v *int) { require.Equal(t, 5, *v) })
+				close(readDone)
+			}()
+			select {
+			case <-readDone:
+			case <-time.After(time.Second):
+				t.Error("reader blocked after downgrade")
+			}
+		},
+	)
+}
+
+func TestRWArcMutex_UpgradableNilAndClosed(t *testing.T) {
+	var m *RWArcMutex[int]
+	m.WithUpgradableRLock(func(_ *int, _ func(func(*int))) { t.Fail() })
+	m.WithLockDowngrade(func(_ *int) { t.Fail() }, func(_ *int) { t.Fail() })
+
+	m2 := NewRWArcMutex(5)
+	m2.Drop()
+	m2.WithUpgradableRLock(func(_ *int, _ func(func(*int))) { t.Fail() })
+	m2.WithLockDowngrade(func(_ *int) { t.Fail() }, func(_ *int) { t.Fail() })
+}
+
+func TestRWArcMutex_ErrDropped(t *testing.T) {
+	var nilMutex *RWArcMutex[int]
+	require.ErrorIs(t, nilMutex.WithRLock(func(_ *int) {}), ErrDropped)
+	require.ErrorIs(t, nilMutex.WithLock(func(_ *int) {}), ErrDropped)
+
+	m := NewRWArcMutex(1)
+	require.NoError(t, m.WithRLock(func(_ *int) {}))
+	require.NoError(t, m.WithLock(func(_ *int) {}))
+	require.NoError(t, m.WithUpgradableRLock(func(_ *int, _ func(func(*int))) {}))
+	require.NoError(t, m.WithLockDowngrade(func(_ *int) {}, func(_ *int) {}))
+
+	m.Drop()
+	require.ErrorIs(t, m.WithRLock(func(_ *int) {}), ErrDropped)
+	require.ErrorIs(t, m.WithLock(func(_ *int) {}), ErrDropped)
+	require.ErrorIs(t, m.WithUpgradableRLock(func(_ *int, _ func(func(*int))) {}), ErrDropped)
+	require.ErrorIs(t, m.WithLockDowngrade(func(_ *int) {}, func(_ *int) {}), ErrDropped)
+	require.Nil(t, m.Clone())
+
+	m.Drop() // must not go negative
+	require.Equal(t, int64(0), m.RefCount())
+}
+
+func TestRWArcMutex_DropWhileHeld(t *testing.T) {
+	m := NewRWArcMutex(7)
+	clone := m.Clone()
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- clone.WithRLock(func(v *int) {
+			close(entered)
+			<-release
+			// The value stays valid for holders that were inside before Drop.
+			require.Equal(t, 7, *v)
+		})
+	}()
+	<-entered
+
+	require.False(t, clone.Drop())
+	dropped := make(chan bool)
+	go func() { dropped <- m.Drop() }()
+
+	// The last Drop closes the mutex right away but waits for the holder.
+	require.Eventually(t, func() bool { return m.shared.closed.Load() }, time.Second, time.Millisecond)
+	require.ErrorIs(t, m.WithRLock(func(_ *int) { t.Fail() }), ErrDropped)
+	select {
+	case <-dropped:
+		t.Fatal("Drop returned while a holder was still inside")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+	require.True(t, <-dropped)
+	require.Nil(t, m.shared.value)
+}
+
+func TestRWArcMutex_DropWhileWaitingForLock(t *testing.T) {
+	m := NewRWArcMutex(7)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	go func() {
+		_ = m.WithLock(func(_ *int) {
+			close(entered)
+			<-release
+		})
+	}()
+	<-entered
+
+	// This writer queues behind the holder and acquires the lock after Drop.
+	queued := make(chan error)
+	go func() {
+		queued <- m.WithLock(func(_ *int) { t.Error("fn must not run after Drop") })
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	dropped := make(chan bool)
+	go func() { dropped <- m.Drop() }()
+	require.Eventually(t, func() bool { return m.shared.closed.Load() }, time.Second, time.Millisecond)
+
+	close(release)
+	require.ErrorIs(t, <-queued, ErrDropped)
+	require.True(t, <-dropped)
+}
+
+func TestRWArcMutex_DistinctHandles(t *testing.T) {
+	m := NewRWArcMutex(1)
+	clone := m.Clone()
+	require.NotSame(t, m, clone)
+	require.Equal(t, int64(2), m.RefCount())
+
+	// Dropping the same handle twice only releases one reference.
+	require.False(t, clone.Drop())
+	require.False(t, clone.Drop())
+	require.Equal(t, int64(1), m.RefCount())
+
+	// A dropped handle is unusable, but other handles keep working.
+	require.False(t, clone.IsValid())
+	require.Nil(t, clone.Clone())
+	require.ErrorIs(t, clone.WithRLock(func(_ *int) { t.Fail() }), ErrDropped)
+	require.True(t, m.IsValid())
+	require.NoError(t, m.WithLock(func(v *int) { *v = 2 }))
+
+	require.True(t, m.Drop())
+	require.False(t, m.IsValid())
+	require.Equal(t, int64(0), m.RefCount())
+}
@@ -0,0 +1,170 @@
+// Package seqlock provides a sequence lock for small, frequently read values.
+//
+// SeqLock[T] lets readers load a value optimistically without taking any lock:
+// a reader copies the value and retries if a writer modified it concurrently.
+// Writers serialize among themselves and never wait for readers. This makes
+// SeqLock[T] a good fit for small structs such as counters, coordinates or
+// timestamps that are read far more often than they are written.
+//
+// The value is stored as a sequence of machine words accessed with atomic
+// operations, so SeqLock[T] is clean under the race detector. As a consequence
+// T must not contain pointers, strings, slices, maps, channels, functions or
+// interfaces; NewSeqLock panics otherwise.
+//
+// Example usage:
+//
+//	type Point struct{ X, Y float64 }
+//	pos := seqlock.NewSeqLock(Point{})
+//	pos.Store(Point{X: 1, Y: 2})
+//	p := pos.Load()
+//	pos.Update(func(p Point) Point { p.X++; return p })
+package seqlock
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// wordSize is the size in bytes of one storage word.
+const wordSize = 8
+
+// SeqLock is a sequence lock protecting a small pointer-free value of type T.
+type SeqLock[T any] struct {
+	mu    sync.Mutex    // serializes writers
+	seq   atomic.Uint64 // odd while a write is in progress
+	words []atomic.Uint64
+	size  int
+}
+
+// NewSeqLock creates a new SeqLock holding the given initial value.
+// It panics if T contains pointers.
+//
+// Example:
+//
+//	counter := NewSeqLock(Stats{})
+func NewSeqLock[T any](value T) *SeqLock[T] {
+	if t := reflect.TypeFor[T](); hasPointers(t) {
+		panic(fmt.Sprintf("seqlock: type %v must not contain pointers", t))
+	}
+	size := int(unsafe.Sizeof(value))
+	s := &SeqLock[T]{
+		words: make([]atomic.Uint64, (size+wordSize-1)/wordSize),
+		size:  size,
+	}
+	s.write(&value)
+	return s
+}
+
+// hasPointers reports whether values of type t contain anything the garbage
+// collector has to trace, which cannot be copied word by word safely.
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+// bytesOf returns the memory of *v as a byte slice.
+func (s *SeqLock[T]) bytesOf(v *T) []byte {
+	return unsafe.Slice((*byte)(unsafe.Pointer(v)), s.size)
+}
+
+// write stores *v into the words. The caller must hold s.mu.
+func (s *SeqLock[T]) write(v *T) {
+	src := s.bytesOf(v)
+	for i := range s.words {
+		var buf [wordSize]byte // zero-padded for a trailing partial word
+		copy(buf[:], src[i*wordSize:])
+		s.words[i].Store(binary.LittleEndian.Uint64(buf[:]))
+	}
+}
+
+// read copies the words into *v. The result is only consistent if no write
+// overlapped with the copy.
+func (s *SeqLock[T]) read(v *T) {
+	dst := s.bytesOf(v)
+	var buf [wordSize]byte
+	for i := range s.words {
+		binary.LittleEndian.PutUint64(buf[:], s.words[i].Load())
+		copy(dst[i*wordSize:], buf[:])
+	}
+}
+
+// Load returns a consistent copy of the current value. It never blocks
+// writers; if a write is in progress or completes while the value is being
+// copied, Load retries.
+func (s *SeqLock[T]) Load() T {
+	var v T
+	for {
+		seq := s.seq.Load()
+		if seq&1 != 0 {
+			// A writer is in the middle of an update.
+			runtime.Gosched()
+			continue
+		}
+		s.read(&v)
+		if s.seq.Load() == seq {
+			return v
+		}
+	}
+}
+
+// Store replaces the current value.
+func (s *SeqLock[T]) Store(value T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq.Add(1)
+	s.write(&value)
+	s.seq.Add(1)
+}
+
+// Update replaces the current value with the result of fn. Concurrent
+// writers are serialized, so fn always sees the latest stored value.
+//
+// Example:
+//
+//	hits.Update(func(c Counter) Counter {
+//		c.Total++
+//		return c
+//	})
+func (s *SeqLock[T]) Update(fn func(T) T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var current T
+	// Writers are serialized by s.mu, so no write can overlap this read.
+	s.read(&current)
+	next := fn(current)
+	s.seq.Add(1)
+	s.write(&next)
+	s.seq.Add(1)
+}
+
+// Sequence returns the current sequence number. It increases by two with
+// every completed write and is odd while a write is in progress.
+func (s *SeqLock[T]) Sequence() uint64 {
+	return s.seq.Load()
+}
+
+// String returns a string representation of the SeqLock.
+func (s *SeqLock[T]) String() string {
+	return fmt.Sprintf("SeqLock{seq=%d, size=%d}", s.Sequence(), s.size)
+}
@@ -0,0 +1,195 @@
+package seqlock
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/Gosayram/gokoncurent/pkg/rwarcmutex"
+)
+
+type point struct {
+	X, Y, Z float64
+}
+
+// odd has a size that is not a multiple of the storage word size.
+type odd struct {
+	A int32
+	B bool
+	C [6]byte
+	D uint16
+}
+
+func TestNewSeqLock(t *testing.T) {
+	s := NewSeqLock(point{1, 2, 3})
+	require.Equal(t, point{1, 2, 3}, s.Load())
+	require.Equal(t, uint64(0), s.Sequence())
+}
+
+func TestSeqLock_StoreAndUpdate(t *testing.T) {
+	s := NewSeqLock(0)
+	s.Store(41)
+	s.Update(func(v int) int { return v + 1 })
+	require.Equal(t, 42, s.Load())
+	require.Equal(t, uint64(4), s.Sequence())
+}
+
+func TestSeqLock_OddSizes(t *testing.T) {
+	o := odd{A: -7, B: true, C: [6]byte{1, 2, 3, 4, 5, 6}, D: 0xBEEF}
+	s := NewSeqLock(o)
+	require.Equal(t, o, s.Load())
+
+	b := NewSeqLock([3]byte{7, 8, 9})
+	require.Equal(t, [3]byte{7, 8, 9}, b.Load())
+
+	e := NewSeqLock(struct{}{})
+	e.Store(struct{}{})
+	require.Equal(t, struct{}{}, e.Load())
+
+	ts := NewSeqLock(time.Duration(5))
+	require.Equal(t, time.Duration(5), ts.Load())
+}
+
+func TestSeqLock_RejectsPointers(t *testing.T) {
+	require.Panics(t, func() { NewSeqLock("string") })
+	require.Panics(t, func() { NewSeqLock([]int{1}) })
+	require.Panics(t, func() { NewSeqLock(&point{}) })
+	require.Panics(t, func() { NewSeqLock(map[int]int{}) })
+	require.Panics(t, func() { NewSeqLock[any](1) })
+	require.Panics(t, func() {
+		NewSeqLock(struct {
+			N int
+			P *int
+		}{})
+	})
+	// time.Time carries a *Location pointer.
+	require.Panics(t, func() { NewSeqLock(time.Time{}) })
+}
+
+func TestSeqLock_ConcurrentConsistency(t *testing.T) {
+	s := NewSeqLock(point{})
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				p := s.Load()
+				if p.Y != 2*p.X || p.Z != -p.X {
+					t.Errorf("torn read: %+v", p)
+					return
+				}
+			}
+		}()
+	}
+
+	var writers sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		writers.Add(1)
+		go func() {
+			defer writers.Done()
+			for j := 0; j < 2000; j++ {
+				s.Update(func(p point) point {
+					x := p.X + 1
+					return point{X: x, Y: 2 * x, Z: -x}
+				})
+			}
+		}()
+	}
+	writers.Wait()
+	close(stop)
+	wg.Wait()
+
+	require.Equal(t, point{4000, 8000, -4000}, s.Load())
+	require.Equal(t, uint64(8000), s.Sequence())
+}
+
+func TestSeqLock_String(t *testing.T) {
+	s := NewSeqLock(int32(1))
+	require.Equal(t, "SeqLock{seq=0, size=4}", s.String())
+}
+
+func BenchmarkSeqLockLoad(b *testing.B) {
+	s := NewSeqLock(point{1, 2, 3})
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = s.Load()
+		}
+	})
+}
+
+func BenchmarkRWArcMutexLoad(b *testing.B) {
+	m := rwarcmutex.NewRWArcMutex(point{1, 2, 3})
+	defer m.Drop()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var p point
+			_ = m.WithRLock(func(v *point) { p = *v })
+			_ = p
+		}
+	})
+}
+
+func BenchmarkSeqLockLoadWithWriter(b *testing.B) {
+	s := NewSeqLock(point{1, 2, 3})
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				s.Update(func(p point) point { p.X++; return p })
+				time.Sleep(time.Microsecond)
+			}
+		}
+	}()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = s.Load()
+		}
+	})
+}
+
+func BenchmarkRWArcMutexLoadWithWriter(b *testing.B) {
+	m := rwarcmutex.NewRWArcMutex(point{1, 2, 3})
+	defer m.Drop()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = m.WithLock(func(p *point) { p.X++ })
+				time.Sleep(time.Microsecond)
+			}
+		}
+	}()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var p point
+			_ = m.WithRLock(func(v *point) { p = *v })
+			_ = p
+		}
+	})
+}